@@ -1,6 +1,7 @@
 package newrelic
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/hashicorp/terraform/helper/logging"
@@ -9,8 +10,18 @@ import (
 
 // Config contains New Relic provider settings
 type Config struct {
-	APIKey string
-	APIURL string
+	APIKey           string
+	APIURL           string
+	InsightsQueryKey string
+	InsightsURL      string
+	AccountID        int
+}
+
+// ProviderConfig contains the clients used by resources and data sources.
+// InsightsClient is nil unless an Insights query key and account ID are set.
+type ProviderConfig struct {
+	Client         *newrelic.Client
+	InsightsClient *newrelic.InsightsClient
 }
 
 // Client returns a new client for accessing New Relic
@@ -27,3 +38,32 @@ func (c *Config) Client() (*newrelic.Client, error) {
 
 	return &client, nil
 }
+
+// InsightsClient returns a new client for querying New Relic Insights
+func (c *Config) InsightsClient() (*newrelic.InsightsClient, error) {
+	if c.InsightsQueryKey == "" || c.AccountID == 0 {
+		return nil, nil
+	}
+
+	client := newrelic.NewInsights(newrelic.InsightsConfig{
+		QueryKey:  c.InsightsQueryKey,
+		AccountID: c.AccountID,
+		Debug:     logging.IsDebugOrHigher(),
+		BaseURL:   c.InsightsURL,
+	})
+
+	log.Printf("[INFO] New Relic Insights client configured")
+
+	return &client, nil
+}
+
+// insightsClient returns the Insights client of the provider, or an error
+// if the provider was not configured for Insights queries.
+func insightsClient(meta interface{}) (*newrelic.InsightsClient, error) {
+	client := meta.(*ProviderConfig).InsightsClient
+	if client == nil {
+		return nil, fmt.Errorf("insights_query_key and account_id must be set in the provider configuration to query Insights")
+	}
+
+	return client, nil
+}
@@ -0,0 +1,233 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+// insightsMaxLimit is the largest LIMIT an Insights query accepts.
+const insightsMaxLimit = 1000
+
+func dataSourceNewRelicInsightsQuery() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceNewRelicInsightsQueryRead,
+
+		Schema: map[string]*schema.Schema{
+			"query": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateInsightsQuery,
+			},
+			"timeout": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      30,
+				ValidateFunc: validation.IntBetween(1, 600),
+			},
+			"max_rows": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      insightsMaxLimit,
+				ValidateFunc: validation.IntBetween(1, insightsMaxLimit),
+			},
+			"result": {
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+			"results": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeMap},
+			},
+			"facets": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeMap},
+			},
+			"facet_names": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// flattenInsightsValue adds a result value to m as strings, joining the keys
+// of nested maps and lists with dots, e.g. "percentile.95" or "members.0".
+func flattenInsightsValue(m map[string]interface{}, key string, v interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, nested := range value {
+			flattenInsightsValue(m, key+"."+k, nested)
+		}
+	case []interface{}:
+		for i, nested := range value {
+			flattenInsightsValue(m, key+"."+strconv.Itoa(i), nested)
+		}
+	case float64:
+		m[key] = strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		m[key] = ""
+	default:
+		m[key] = fmt.Sprintf("%v", value)
+	}
+}
+
+func flattenInsightsRow(row map[string]interface{}) map[string]interface{} {
+	m := map[string]interface{}{}
+	for k, v := range row {
+		flattenInsightsValue(m, k, v)
+	}
+	return m
+}
+
+// flattenInsightsQueryRows returns the rows of a query result: the events of
+// an event query, or one row per aggregate function otherwise.
+func flattenInsightsQueryRows(results []map[string]interface{}) []interface{} {
+	var rows []interface{}
+
+	for _, result := range results {
+		if events, ok := result["events"].([]interface{}); ok && len(result) == 1 {
+			for _, event := range events {
+				if e, ok := event.(map[string]interface{}); ok {
+					rows = append(rows, flattenInsightsRow(e))
+				}
+			}
+			continue
+		}
+
+		rows = append(rows, flattenInsightsRow(result))
+	}
+
+	return rows
+}
+
+// flattenInsightsQueryFacets returns one row per facet, holding the facet
+// name under "name" and its aggregate results.
+func flattenInsightsQueryFacets(facets []newrelic.InsightsFacet) ([]interface{}, []interface{}) {
+	var rows, names []interface{}
+
+	for _, facet := range facets {
+		name := insightsFacetName(facet.Name)
+
+		row := map[string]interface{}{}
+		for _, result := range facet.Results {
+			for k, v := range flattenInsightsRow(result) {
+				row[k] = v
+			}
+		}
+		row["name"] = name
+
+		rows = append(rows, row)
+		names = append(names, name)
+	}
+
+	return rows, names
+}
+
+// insightsQueryHasTimeSeries reports whether a query result, or any of its
+// facets, holds TIMESERIES buckets.
+func insightsQueryHasTimeSeries(resp *newrelic.InsightsQueryResponse) bool {
+	if len(resp.TimeSeries) > 0 {
+		return true
+	}
+
+	for _, facet := range resp.Facets {
+		if len(facet.TimeSeries) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// insightsQueryLimit returns the query with its LIMIT lowered to maxRows, or
+// with LIMIT maxRows added if it has none, so that no more events or facets
+// than that are fetched. Queries other than SELECT queries are left as is.
+func insightsQueryLimit(query string, maxRows int) string {
+	q, err := parseNrql(query)
+	if err != nil || q.clause("SELECT") == nil {
+		return query
+	}
+
+	clause := q.clause("LIMIT")
+	if clause == nil {
+		return fmt.Sprintf("%s LIMIT %d", query, maxRows)
+	}
+
+	if len(clause.args) == 1 && clause.args[0].kind == nrqlNumber {
+		if n, err := strconv.Atoi(clause.args[0].value); err == nil && n <= maxRows {
+			return query
+		}
+	}
+
+	last := clause.args[len(clause.args)-1]
+	end := last.pos - 1 + len(last.value)
+
+	return fmt.Sprintf("%sLIMIT %d%s", query[:clause.pos-1], maxRows, query[end:])
+}
+
+func dataSourceNewRelicInsightsQueryRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := insightsClient(meta)
+	if err != nil {
+		return err
+	}
+
+	query := d.Get("query").(string)
+	timeout := time.Duration(d.Get("timeout").(int)) * time.Second
+
+	nrql := insightsQueryLimit(query, d.Get("max_rows").(int))
+
+	log.Printf("[INFO] Querying New Relic Insights: %s", nrql)
+
+	resp, err := client.WithTimeout(timeout).Query(nrql)
+	if err != nil {
+		return err
+	}
+
+	if insightsQueryHasTimeSeries(resp) {
+		return fmt.Errorf("Insights query returned a TIMESERIES result, which is not supported")
+	}
+
+	rows := flattenInsightsQueryRows(resp.Results)
+	facets, names := flattenInsightsQueryFacets(resp.Facets)
+
+	// result merges the aggregate results of a query without facets, e.g.
+	// SELECT count(*), average(duration), into a single map.
+	result := map[string]interface{}{}
+	if len(rows) == len(resp.Results) {
+		for _, row := range rows {
+			for k, v := range row.(map[string]interface{}) {
+				result[k] = v
+			}
+		}
+	}
+
+	d.SetId(strconv.Itoa(hashcode.String(query)))
+
+	if err := d.Set("result", result); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting Insights query result: %#v", err)
+	}
+
+	if err := d.Set("results", rows); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting Insights query results: %#v", err)
+	}
+
+	if err := d.Set("facets", facets); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting Insights query facets: %#v", err)
+	}
+
+	if err := d.Set("facet_names", names); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting Insights query facet names: %#v", err)
+	}
+
+	return nil
+}
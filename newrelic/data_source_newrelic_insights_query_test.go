@@ -0,0 +1,149 @@
+package newrelic
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+func TestAccNewRelicInsightsQuery_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccInsightsPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccNewRelicInsightsQueryConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrSet(
+						"data.newrelic_insights_query.count", "result.count"),
+					resource.TestCheckResourceAttr(
+						"data.newrelic_insights_query.apps", "facet_names.0", testAccExpectedApplicationName),
+					resource.TestCheckResourceAttr(
+						"data.newrelic_insights_query.apps", "facets.0.name", testAccExpectedApplicationName),
+				),
+			},
+		},
+	})
+}
+
+// The test application for this data source is created in provider_test.go
+func testAccNewRelicInsightsQueryConfig() string {
+	return fmt.Sprintf(`
+data "newrelic_insights_query" "count" {
+  query = "SELECT count(*) FROM Transaction SINCE 1 day ago"
+}
+
+data "newrelic_insights_query" "apps" {
+  query    = "SELECT count(*) FROM Transaction WHERE appName = '%s' FACET appName SINCE 1 day ago"
+  timeout  = 60
+  max_rows = 10
+}
+`, testAccExpectedApplicationName)
+}
+
+func TestFlattenInsightsQueryRows(t *testing.T) {
+	cases := []struct {
+		results []map[string]interface{}
+		rows    []interface{}
+	}{
+		{
+			results: []map[string]interface{}{
+				{"count": 42.0},
+				{"percentile": map[string]interface{}{"95": 1.25}},
+			},
+			rows: []interface{}{
+				map[string]interface{}{"count": "42"},
+				map[string]interface{}{"percentile.95": "1.25"},
+			},
+		},
+		{
+			results: []map[string]interface{}{
+				{"members": []interface{}{"a", "b"}},
+			},
+			rows: []interface{}{
+				map[string]interface{}{"members.0": "a", "members.1": "b"},
+			},
+		},
+		{
+			results: []map[string]interface{}{
+				{"events": []interface{}{
+					map[string]interface{}{"host": "a", "error": true, "duration": nil},
+					map[string]interface{}{"host": "b", "error": false, "duration": 0.5},
+				}},
+			},
+			rows: []interface{}{
+				map[string]interface{}{"host": "a", "error": "true", "duration": ""},
+				map[string]interface{}{"host": "b", "error": "false", "duration": "0.5"},
+			},
+		},
+	}
+
+	for i, c := range cases {
+		rows := flattenInsightsQueryRows(c.results)
+		if !reflect.DeepEqual(rows, c.rows) {
+			t.Fatalf("case %d: expected %#v, got %#v", i, c.rows, rows)
+		}
+	}
+}
+
+func TestFlattenInsightsQueryFacets(t *testing.T) {
+	facets := []newrelic.InsightsFacet{
+		{Name: "web-1", Results: []map[string]interface{}{{"count": 3.0}}},
+		{Name: []interface{}{"web-2", "us-east"}, Results: []map[string]interface{}{{"count": 1.0}}},
+	}
+
+	rows, names := flattenInsightsQueryFacets(facets)
+
+	expectedRows := []interface{}{
+		map[string]interface{}{"name": "web-1", "count": "3"},
+		map[string]interface{}{"name": "web-2, us-east", "count": "1"},
+	}
+	if !reflect.DeepEqual(rows, expectedRows) {
+		t.Fatalf("expected %#v, got %#v", expectedRows, rows)
+	}
+
+	expectedNames := []interface{}{"web-1", "web-2, us-east"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Fatalf("expected %#v, got %#v", expectedNames, names)
+	}
+}
+
+func TestInsightsQueryHasTimeSeries(t *testing.T) {
+	if insightsQueryHasTimeSeries(&newrelic.InsightsQueryResponse{}) {
+		t.Fatal("expected no time series")
+	}
+
+	resp := &newrelic.InsightsQueryResponse{
+		Facets: []newrelic.InsightsFacet{
+			{Name: "web-1", TimeSeries: []newrelic.InsightsTimeSeriesBucket{{BeginTimeSeconds: 1500000000}}},
+		},
+	}
+	if !insightsQueryHasTimeSeries(resp) {
+		t.Fatal("expected faceted time series")
+	}
+}
+
+func TestInsightsQueryLimit(t *testing.T) {
+	cases := []struct {
+		query    string
+		maxRows  int
+		expected string
+	}{
+		{"SELECT * FROM Transaction", 100, "SELECT * FROM Transaction LIMIT 100"},
+		{"SELECT count(*) FROM Transaction FACET host LIMIT 20", 100, "SELECT count(*) FROM Transaction FACET host LIMIT 20"},
+		{"SELECT count(*) FROM Transaction FACET host LIMIT 500 SINCE 1 day ago", 100, "SELECT count(*) FROM Transaction FACET host LIMIT 100 SINCE 1 day ago"},
+		{"SELECT count(*) FROM Transaction FACET host limit max", 1000, "SELECT count(*) FROM Transaction FACET host LIMIT 1000"},
+	}
+
+	for i, c := range cases {
+		if actual := insightsQueryLimit(c.query, c.maxRows); actual != c.expected {
+			t.Fatalf("case %d: expected %q, got %q", i, c.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+// nrqlBacktestBuckets is the most TIMESERIES buckets a single Insights query
+// returns, so the backtest window is queried in chunks of this many minutes.
+const nrqlBacktestBuckets = 366
+
+func dataSourceNewRelicNrqlAlertConditionBacktest() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceNewRelicNrqlAlertConditionBacktestRead,
+
+		Schema: map[string]*schema.Schema{
+			"nrql":     nrqlAlertConditionQuerySchema(),
+			"term":     nrqlAlertConditionTermSchema(),
+			"critical": alertConditionThresholdSchema(nrqlAlertConditionDurations),
+			"warning":  alertConditionThresholdSchema(nrqlAlertConditionDurations),
+			"value_function": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "single_value",
+				ValidateFunc: validation.StringInSlice([]string{"single_value", "sum"}, false),
+			},
+			"days": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				Default:      7,
+				ValidateFunc: validation.IntBetween(1, 7),
+			},
+			"evaluated_minutes": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"signals": {
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			"results": {
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"priority": {
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"violations": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"violation_minutes": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+						"longest_violation_minutes": {
+							Type:     schema.TypeInt,
+							Computed: true,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// insightsResultValue returns the value of a single value query result, such
+// as {"count": 3} or {"percentile": {"95": 1.2}}.
+func insightsResultValue(results []map[string]interface{}) (float64, bool) {
+	if len(results) != 1 {
+		return 0, false
+	}
+
+	var v interface{} = results[0]
+	for {
+		switch value := v.(type) {
+		case float64:
+			return value, true
+		case map[string]interface{}:
+			if len(value) != 1 {
+				return 0, false
+			}
+			for _, nested := range value {
+				v = nested
+			}
+		default:
+			return 0, false
+		}
+	}
+}
+
+// insightsFacetName returns the name of a facet, joining the values of a
+// facet on more than one attribute.
+func insightsFacetName(name interface{}) string {
+	if values, ok := name.([]interface{}); ok {
+		var parts []string
+		for _, v := range values {
+			parts = append(parts, fmt.Sprintf("%v", v))
+		}
+		return strings.Join(parts, ", ")
+	}
+
+	return fmt.Sprintf("%v", name)
+}
+
+// nrqlBacktestQuery returns the query for one window of the backtest. Faceted
+// queries get LIMIT MAX unless they set a LIMIT, so that every window returns
+// the same facets rather than its own top 10.
+func nrqlBacktestQuery(query string, since time.Time, until time.Time) string {
+	if q, err := parseNrql(query); err == nil && q.clause("FACET") != nil && q.clause("LIMIT") == nil {
+		query += " LIMIT MAX"
+	}
+
+	return fmt.Sprintf("%s SINCE %d UNTIL %d TIMESERIES 1 minute", query, since.Unix()*1000, until.Unix()*1000)
+}
+
+// queryNrqlBacktestSignals returns the value the condition evaluates for each
+// minute from start on, by facet for faceted queries. As with since_value, the
+// value at a minute is that of the data sinceValue minutes earlier. Minutes
+// without data are NaN.
+func queryNrqlBacktestSignals(client *newrelic.InsightsClient, query string, sinceValue int, start time.Time, minutes int) (map[string][]float64, error) {
+	signals := map[string][]float64{}
+
+	addBuckets := func(name string, buckets []newrelic.InsightsTimeSeriesBucket) {
+		values, ok := signals[name]
+		if !ok {
+			values = make([]float64, minutes)
+			for i := range values {
+				values[i] = math.NaN()
+			}
+			signals[name] = values
+		}
+
+		for _, bucket := range buckets {
+			i := int((bucket.BeginTimeSeconds-start.Unix())/60) + sinceValue
+			if i < 0 || i >= minutes {
+				continue
+			}
+
+			if v, ok := insightsResultValue(bucket.Results); ok {
+				values[i] = v
+			}
+		}
+	}
+
+	for offset := 0; offset < minutes; offset += nrqlBacktestBuckets {
+		n := minutes - offset
+		if n > nrqlBacktestBuckets {
+			n = nrqlBacktestBuckets
+		}
+
+		since := start.Add(time.Duration(offset-sinceValue) * time.Minute)
+		until := since.Add(time.Duration(n) * time.Minute)
+
+		nrql := nrqlBacktestQuery(query, since, until)
+
+		log.Printf("[INFO] Querying New Relic Insights: %s", nrql)
+
+		resp, err := client.Query(nrql)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resp.Facets) > 0 {
+			for _, facet := range resp.Facets {
+				addBuckets(insightsFacetName(facet.Name), facet.TimeSeries)
+			}
+		} else {
+			addBuckets("", resp.TimeSeries)
+		}
+	}
+
+	return signals, nil
+}
+
+func nrqlBacktestBreaches(operator string, threshold float64, v float64) bool {
+	switch operator {
+	case "above":
+		return v > threshold
+	case "below":
+		return v < threshold
+	default:
+		return v == threshold
+	}
+}
+
+// nrqlBacktestTermMet reports whether the term is met at minute i, looking
+// back over the duration of the term.
+func nrqlBacktestTermMet(term newrelic.AlertConditionTerm, valueFunction string, values []float64, i int) bool {
+	if i+1 < term.Duration {
+		return false
+	}
+
+	window := values[i+1-term.Duration : i+1]
+
+	if valueFunction == "sum" {
+		sum, seen := 0.0, false
+		for _, v := range window {
+			if !math.IsNaN(v) {
+				sum += v
+				seen = true
+			}
+		}
+
+		return seen && nrqlBacktestBreaches(term.Operator, term.Threshold, sum)
+	}
+
+	for _, v := range window {
+		breach := !math.IsNaN(v) && nrqlBacktestBreaches(term.Operator, term.Threshold, v)
+
+		if term.TimeFunction == "any" && breach {
+			return true
+		}
+
+		if term.TimeFunction == "all" && !breach {
+			return false
+		}
+	}
+
+	return term.TimeFunction == "all"
+}
+
+// backtestNrqlAlertConditionTerm returns the length in minutes of each
+// violation the term would have opened on the signal. A violation opens on
+// the first minute the term is met and closes on the first minute it is not.
+func backtestNrqlAlertConditionTerm(term newrelic.AlertConditionTerm, valueFunction string, values []float64) []int {
+	var violations []int
+	open := -1
+
+	for i := range values {
+		if nrqlBacktestTermMet(term, valueFunction, values, i) {
+			if open < 0 {
+				open = i
+			}
+			continue
+		}
+
+		if open >= 0 {
+			violations = append(violations, i-open)
+			open = -1
+		}
+	}
+
+	if open >= 0 {
+		violations = append(violations, len(values)-open)
+	}
+
+	return violations
+}
+
+func dataSourceNewRelicNrqlAlertConditionBacktestRead(d *schema.ResourceData, meta interface{}) error {
+	client, err := insightsClient(meta)
+	if err != nil {
+		return err
+	}
+
+	terms, err := expandAlertConditionTerms(d)
+	if err != nil {
+		return err
+	}
+
+	// Data sources have no plan-time checks, so the terms are checked here.
+	for _, block := range alertConditionTermBlocks(d) {
+		if err := validateAlertConditionThresholdUnit(block.(map[string]interface{})["threshold"].(string), ""); err != nil {
+			return err
+		}
+	}
+
+	if err := validateAlertConditionTerms(terms); err != nil {
+		return err
+	}
+
+	query := d.Get("nrql.0.query").(string)
+	sinceValue, err := strconv.Atoi(d.Get("nrql.0.since_value").(string))
+	if err != nil {
+		return err
+	}
+
+	if q, err := parseNrql(query); err == nil {
+		if err := nrqlAlertQuerySinceError(q, d.Get("nrql.0.since_value").(string)); err != nil {
+			return err
+		}
+	}
+
+	valueFunction := d.Get("value_function").(string)
+	minutes := d.Get("days").(int) * 24 * 60
+
+	start := time.Now().Truncate(time.Minute).Add(-time.Duration(minutes) * time.Minute)
+
+	log.Printf("[INFO] Backtesting New Relic NRQL alert condition over %d minutes", minutes)
+
+	signals, err := queryNrqlBacktestSignals(client, query, sinceValue, start, minutes)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(signals))
+	for name := range signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []interface{}
+	for _, term := range terms {
+		violations, total, longest := 0, 0, 0
+
+		for _, name := range names {
+			for _, length := range backtestNrqlAlertConditionTerm(term, valueFunction, signals[name]) {
+				violations++
+				total += length
+				if length > longest {
+					longest = length
+				}
+			}
+		}
+
+		results = append(results, map[string]interface{}{
+			"priority":                  term.Priority,
+			"violations":                violations,
+			"violation_minutes":         total,
+			"longest_violation_minutes": longest,
+		})
+	}
+
+	d.SetId(strconv.Itoa(hashcode.String(query)))
+	d.Set("evaluated_minutes", minutes)
+	d.Set("signals", len(signals))
+
+	if err := d.Set("results", results); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting backtest results: %#v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,149 @@
+package newrelic
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/resource"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+func TestAccNewRelicNrqlAlertConditionBacktest_Basic(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck: func() {
+			testAccPreCheck(t)
+			testAccInsightsPreCheck(t)
+		},
+		Providers: testAccProviders,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccNewRelicNrqlAlertConditionBacktestConfig(),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr(
+						"data.newrelic_nrql_alert_condition_backtest.foo", "evaluated_minutes", "1440"),
+					resource.TestCheckResourceAttr(
+						"data.newrelic_nrql_alert_condition_backtest.foo", "results.#", "2"),
+					resource.TestCheckResourceAttr(
+						"data.newrelic_nrql_alert_condition_backtest.foo", "results.0.priority", "critical"),
+					resource.TestCheckResourceAttr(
+						"data.newrelic_nrql_alert_condition_backtest.foo", "results.1.priority", "warning"),
+				),
+			},
+		},
+	})
+}
+
+func testAccInsightsPreCheck(t *testing.T) {
+	if os.Getenv("NEWRELIC_INSIGHTS_QUERY_KEY") == "" || os.Getenv("NEWRELIC_ACCOUNT_ID") == "" {
+		t.Fatal("NEWRELIC_INSIGHTS_QUERY_KEY and NEWRELIC_ACCOUNT_ID must be set for Insights acceptance tests")
+	}
+}
+
+func testAccNewRelicNrqlAlertConditionBacktestConfig() string {
+	return fmt.Sprintf(`
+data "newrelic_nrql_alert_condition_backtest" "foo" {
+  days = 1
+
+  nrql {
+    query       = "SELECT count(*) FROM Transaction WHERE appName = '%s'"
+    since_value = "3"
+  }
+
+  critical {
+    operator      = "above"
+    threshold     = "100"
+    duration      = "5m"
+    time_function = "all"
+  }
+
+  warning {
+    operator      = "above"
+    threshold     = "50"
+    duration      = "5m"
+    time_function = "all"
+  }
+}
+`, testAccExpectedApplicationName)
+}
+
+func TestBacktestNrqlAlertConditionTerm(t *testing.T) {
+	nan := math.NaN()
+	values := []float64{1, 5, 6, 7, 2, nan, 8, 9, 1, 6}
+
+	cases := []struct {
+		term          newrelic.AlertConditionTerm
+		valueFunction string
+		violations    []int
+	}{
+		{
+			term:       newrelic.AlertConditionTerm{Operator: "above", Threshold: 4, Duration: 1, TimeFunction: "all"},
+			violations: []int{3, 2, 1},
+		},
+		{
+			term:       newrelic.AlertConditionTerm{Operator: "above", Threshold: 4, Duration: 3, TimeFunction: "all"},
+			violations: []int{1},
+		},
+		{
+			term:       newrelic.AlertConditionTerm{Operator: "above", Threshold: 4, Duration: 2, TimeFunction: "any"},
+			violations: []int{4, 4},
+		},
+		{
+			term:       newrelic.AlertConditionTerm{Operator: "below", Threshold: 2, Duration: 1, TimeFunction: "all"},
+			violations: []int{1, 1},
+		},
+		{
+			term:          newrelic.AlertConditionTerm{Operator: "above", Threshold: 16, Duration: 2, TimeFunction: "all"},
+			valueFunction: "sum",
+			violations:    []int{1},
+		},
+	}
+
+	for i, c := range cases {
+		violations := backtestNrqlAlertConditionTerm(c.term, c.valueFunction, values)
+		if !reflect.DeepEqual(violations, c.violations) {
+			t.Fatalf("case %d: expected violations %v, got %v", i, c.violations, violations)
+		}
+	}
+}
+
+func TestNrqlBacktestQuery(t *testing.T) {
+	since := time.Unix(1500000000, 0)
+	until := since.Add(time.Hour)
+
+	cases := map[string]string{
+		"SELECT count(*) FROM Transaction":                       "SELECT count(*) FROM Transaction SINCE 1500000000000 UNTIL 1500003600000 TIMESERIES 1 minute",
+		"SELECT count(*) FROM Transaction FACET appName":         "SELECT count(*) FROM Transaction FACET appName LIMIT MAX SINCE 1500000000000 UNTIL 1500003600000 TIMESERIES 1 minute",
+		"SELECT count(*) FROM Transaction FACET appName LIMIT 5": "SELECT count(*) FROM Transaction FACET appName LIMIT 5 SINCE 1500000000000 UNTIL 1500003600000 TIMESERIES 1 minute",
+	}
+
+	for query, expected := range cases {
+		if actual := nrqlBacktestQuery(query, since, until); actual != expected {
+			t.Fatalf("%s: expected %q, got %q", query, expected, actual)
+		}
+	}
+}
+
+func TestInsightsResultValue(t *testing.T) {
+	cases := []struct {
+		results []map[string]interface{}
+		value   float64
+		ok      bool
+	}{
+		{[]map[string]interface{}{{"count": 3.0}}, 3, true},
+		{[]map[string]interface{}{{"percentile": map[string]interface{}{"95": 1.5}}}, 1.5, true},
+		{[]map[string]interface{}{{"average": nil}}, 0, false},
+		{[]map[string]interface{}{{"count": 3.0, "average": 1.0}}, 0, false},
+		{nil, 0, false},
+	}
+
+	for i, c := range cases {
+		value, ok := insightsResultValue(c.results)
+		if value != c.value || ok != c.ok {
+			t.Fatalf("case %d: expected %v %v, got %v %v", i, c.value, c.ok, value, ok)
+		}
+	}
+}
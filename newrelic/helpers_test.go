@@ -1,6 +1,16 @@
 package newrelic
 
-import "testing"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/hil/ast"
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
 
 func TestParseIDs_Basic(t *testing.T) {
 	ids, err := parseIDs("1:2", 2)
@@ -24,3 +34,43 @@ func TestSerializeIDs_Basic(t *testing.T) {
 		t.Fatal(id)
 	}
 }
+
+// testResourceDiff plans r for the raw configuration against state, which is
+// nil for a new resource, running any CustomizeDiff of the resource. The
+// configuration may reference ${var.unknown}, a value not known at plan time.
+func testResourceDiff(t *testing.T, r *schema.Resource, state *terraform.InstanceState, raw map[string]interface{}, meta interface{}) (*terraform.InstanceDiff, error) {
+	c, err := config.NewRawConfig(raw)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	unknown := map[string]ast.Variable{
+		"var.unknown": {Value: config.UnknownVariableValue, Type: ast.TypeUnknown},
+	}
+	if err := c.Interpolate(unknown); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	return r.Diff(state, terraform.NewResourceConfig(c), meta)
+}
+
+// testProviderConfig returns a ProviderConfig whose client is served by a
+// local server answering each API path with the given JSON body. The server
+// is closed by the returned function.
+func testProviderConfig(t *testing.T, responses map[string]string) (*ProviderConfig, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+
+	client := newrelic.New(newrelic.Config{APIKey: "foo", BaseURL: server.URL})
+
+	return &ProviderConfig{Client: &client}, server.Close
+}
@@ -19,6 +19,29 @@ func TestAccNewRelicAlertChannel_import(t *testing.T) {
 				Config: testAccCheckNewRelicAlertChannelConfig(rName),
 			},
 
+			resource.TestStep{
+				ResourceName:      resourceName,
+				ImportState:       true,
+				ImportStateVerify: true,
+				// an imported channel is read into its typed block
+				ImportStateVerifyIgnore: []string{"configuration", "email"},
+			},
+		},
+	})
+}
+
+func TestAccNewRelicAlertChannel_importTypedBlock(t *testing.T) {
+	resourceName := "newrelic_alert_channel.foo"
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertChannelDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertChannelConfigTypedBlock(rName),
+			},
+
 			resource.TestStep{
 				ResourceName:      resourceName,
 				ImportState:       true,
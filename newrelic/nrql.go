@@ -0,0 +1,318 @@
+package newrelic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type nrqlTokenKind int
+
+const (
+	nrqlIdentifier nrqlTokenKind = iota
+	nrqlString
+	nrqlNumber
+	nrqlSymbol
+)
+
+type nrqlToken struct {
+	kind  nrqlTokenKind
+	value string
+	pos   int
+}
+
+// keyword reports whether the token is the given keyword, which NRQL matches
+// case-insensitively.
+func (t nrqlToken) keyword(k string) bool {
+	return t.kind == nrqlIdentifier && strings.EqualFold(t.value, k)
+}
+
+func isNrqlIdentifierStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNrqlIdentifierPart(c byte) bool {
+	return isNrqlIdentifierStart(c) || c == '.' || (c >= '0' && c <= '9')
+}
+
+func isNrqlDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// lexNrql splits a query into tokens. Positions are 1-based for messages.
+func lexNrql(query string) ([]nrqlToken, error) {
+	var tokens []nrqlToken
+
+	for i := 0; i < len(query); {
+		c := query[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case isNrqlIdentifierStart(c):
+			start := i
+			for i < len(query) && isNrqlIdentifierPart(query[i]) {
+				i++
+			}
+			tokens = append(tokens, nrqlToken{nrqlIdentifier, query[start:i], start + 1})
+
+		case c == '`':
+			end := strings.IndexByte(query[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quoted identifier at position %d", i+1)
+			}
+			tokens = append(tokens, nrqlToken{nrqlIdentifier, query[i+1 : i+1+end], i + 1})
+			i += end + 2
+
+		case c == '\'' || c == '"':
+			start := i
+			i++
+			for i < len(query) && query[i] != c {
+				if query[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(query) {
+				return nil, fmt.Errorf("unterminated string at position %d", start+1)
+			}
+			i++
+			tokens = append(tokens, nrqlToken{nrqlString, query[start:i], start + 1})
+
+		case isNrqlDigit(c) || (c == '.' && i+1 < len(query) && isNrqlDigit(query[i+1])):
+			start := i
+			for i < len(query) && (isNrqlDigit(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, nrqlToken{nrqlNumber, query[start:i], start + 1})
+
+		case strings.IndexByte("(),*+-/%=<>!", c) >= 0:
+			symbol := query[i : i+1]
+			if i+1 < len(query) {
+				switch query[i : i+2] {
+				case "!=", "<>", "<=", ">=":
+					symbol = query[i : i+2]
+				}
+			}
+			if symbol == "!" {
+				return nil, fmt.Errorf("unexpected character '!' at position %d", i+1)
+			}
+			tokens = append(tokens, nrqlToken{nrqlSymbol, symbol, i + 1})
+			i += len(symbol)
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+		}
+	}
+
+	return tokens, nil
+}
+
+// nrqlClause is a top level clause of a query, such as SELECT or WHERE,
+// together with the tokens of its arguments.
+type nrqlClause struct {
+	name string
+	pos  int
+	args []nrqlToken
+}
+
+type nrqlQuery struct {
+	clauses []nrqlClause
+}
+
+// clause returns the clause with the given name, or nil.
+func (q *nrqlQuery) clause(name string) *nrqlClause {
+	for i := range q.clauses {
+		if q.clauses[i].name == name {
+			return &q.clauses[i]
+		}
+	}
+
+	return nil
+}
+
+// nrqlClauses lists the clause keywords, with the keyword that must follow
+// for two word clauses, and whether the clause requires arguments.
+var nrqlClauses = []struct {
+	name     string
+	first    string
+	second   string
+	required bool
+}{
+	{"SELECT", "SELECT", "", true},
+	{"FROM", "FROM", "", true},
+	{"SHOW", "SHOW", "", true},
+	{"WHERE", "WHERE", "", true},
+	{"FACET", "FACET", "", true},
+	{"SINCE", "SINCE", "", true},
+	{"UNTIL", "UNTIL", "", true},
+	{"COMPARE WITH", "COMPARE", "WITH", true},
+	{"TIMESERIES", "TIMESERIES", "", false},
+	{"LIMIT", "LIMIT", "", true},
+	{"WITH TIMEZONE", "WITH", "TIMEZONE", true},
+	{"EXTRAPOLATE", "EXTRAPOLATE", "", false},
+}
+
+// parseNrql checks the structure of a query: balanced parentheses, no
+// repeated clauses and no clauses missing arguments. Queries other than
+// SELECT queries, such as SHOW EVENT TYPES, are accepted too; alert
+// conditions require SELECT and FROM clauses in nrqlAlertQueryErrors.
+func parseNrql(query string) (*nrqlQuery, error) {
+	tokens, err := lexNrql(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("query is empty")
+	}
+
+	q := &nrqlQuery{}
+	depth := 0
+	var open []int
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		if t.kind == nrqlSymbol {
+			switch t.value {
+			case "(":
+				depth++
+				open = append(open, t.pos)
+			case ")":
+				if depth == 0 {
+					return nil, fmt.Errorf("unexpected ')' at position %d", t.pos)
+				}
+				depth--
+				open = open[:len(open)-1]
+			}
+		}
+
+		if depth == 0 && t.kind == nrqlIdentifier {
+			matched := false
+
+			for _, c := range nrqlClauses {
+				if !t.keyword(c.first) {
+					continue
+				}
+				if c.second != "" && (i+1 >= len(tokens) || !tokens[i+1].keyword(c.second)) {
+					continue
+				}
+
+				if q.clause(c.name) != nil {
+					return nil, fmt.Errorf("%s clause at position %d is repeated", c.name, t.pos)
+				}
+
+				q.clauses = append(q.clauses, nrqlClause{name: c.name, pos: t.pos})
+				if c.second != "" {
+					i++
+				}
+				matched = true
+				break
+			}
+
+			if matched {
+				continue
+			}
+		}
+
+		if len(q.clauses) == 0 {
+			return nil, fmt.Errorf("expected SELECT, FROM or SHOW at position %d, got %q", t.pos, t.value)
+		}
+
+		last := &q.clauses[len(q.clauses)-1]
+		last.args = append(last.args, t)
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("unclosed '(' at position %d", open[len(open)-1])
+	}
+
+	for _, c := range nrqlClauses {
+		clause := q.clause(c.name)
+		if clause == nil {
+			continue
+		}
+
+		if c.required && len(clause.args) == 0 {
+			return nil, fmt.Errorf("%s clause at position %d is missing its arguments", c.name, clause.pos)
+		}
+
+		if n := len(clause.args); n > 0 {
+			if last := clause.args[n-1]; last.kind == nrqlSymbol && last.value != ")" && last.value != "*" {
+				return nil, fmt.Errorf("unexpected %q at position %d at the end of the %s clause", last.value, last.pos, c.name)
+			}
+		}
+	}
+
+	return q, nil
+}
+
+// nrqlSinceMinutes returns the number of minutes of a SINCE clause of the form
+// "SINCE <n> minute(s) ago", or false for any other form.
+func nrqlSinceMinutes(clause *nrqlClause) (int, bool) {
+	if len(clause.args) != 3 || clause.args[0].kind != nrqlNumber {
+		return 0, false
+	}
+
+	if !clause.args[1].keyword("minute") && !clause.args[1].keyword("minutes") {
+		return 0, false
+	}
+
+	if !clause.args[2].keyword("ago") {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(clause.args[0].value)
+	if err != nil {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// nrqlAlertQueryErrors returns the clauses alert conditions require that the
+// query lacks, and those they do not allow as the time window is set by
+// since_value. SINCE is checked against since_value by nrqlAlertQuerySinceError.
+func nrqlAlertQueryErrors(q *nrqlQuery) []error {
+	var es []error
+
+	for _, name := range []string{"SELECT", "FROM"} {
+		if q.clause(name) == nil {
+			es = append(es, fmt.Errorf("query has no %s clause", name))
+		}
+	}
+
+	for _, name := range []string{"UNTIL", "TIMESERIES", "COMPARE WITH"} {
+		if q.clause(name) != nil {
+			es = append(es, fmt.Errorf("%s clause is not allowed in alert conditions", name))
+		}
+	}
+
+	return es
+}
+
+// nrqlAlertQuerySinceError rejects a SINCE clause in an alert condition query,
+// pointing out when it does not match the condition's since_value. An empty
+// sinceValue is one that is not known yet.
+func nrqlAlertQuerySinceError(q *nrqlQuery, sinceValue string) error {
+	clause := q.clause("SINCE")
+	if clause == nil {
+		return nil
+	}
+
+	n, ok := nrqlSinceMinutes(clause)
+	if !ok || n < 1 || n > 5 {
+		return fmt.Errorf("SINCE clause is not allowed in alert conditions, and since_value only supports 1 to 5 minutes")
+	}
+
+	switch sinceValue {
+	case "":
+		return fmt.Errorf("SINCE clause is not allowed in alert conditions, set since_value = \"%d\" instead", n)
+	case strconv.Itoa(n):
+		return fmt.Errorf("SINCE clause is not allowed in alert conditions, remove it as since_value is already \"%d\"", n)
+	default:
+		return fmt.Errorf("SINCE %d minutes ago does not match since_value = %q; SINCE clause is not allowed in alert conditions, set since_value = \"%d\" instead", n, sinceValue, n)
+	}
+}
@@ -0,0 +1,95 @@
+package newrelic
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNrql(t *testing.T) {
+	cases := []struct {
+		query   string
+		clauses []string
+	}{
+		{"SELECT count(*) FROM Transaction", []string{"SELECT", "FROM"}},
+		{"from Transaction select count(*) where appName = 'foo' facet host limit 10", []string{"FROM", "SELECT", "WHERE", "FACET", "LIMIT"}},
+		{"SELECT filter(count(*), WHERE error IS true) FROM Transaction", []string{"SELECT", "FROM"}},
+		{"SELECT count(*) FROM Transaction WHERE `request.uri` LIKE '%/api/%' SINCE 1 day ago COMPARE WITH 1 week ago", []string{"SELECT", "FROM", "WHERE", "SINCE", "COMPARE WITH"}},
+		{"SELECT count(*) FROM PageView WHERE name != \"it's\" WITH TIMEZONE 'America/New_York' TIMESERIES EXTRAPOLATE", []string{"SELECT", "FROM", "WHERE", "WITH TIMEZONE", "TIMESERIES", "EXTRAPOLATE"}},
+		{"SHOW EVENT TYPES SINCE 1 day ago", []string{"SHOW", "SINCE"}},
+	}
+
+	for _, c := range cases {
+		q, err := parseNrql(c.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.query, err)
+		}
+
+		var clauses []string
+		for _, clause := range q.clauses {
+			clauses = append(clauses, clause.name)
+		}
+
+		if strings.Join(clauses, ",") != strings.Join(c.clauses, ",") {
+			t.Fatalf("%s: expected clauses %v, got %v", c.query, c.clauses, clauses)
+		}
+	}
+}
+
+func TestParseNrqlErrors(t *testing.T) {
+	cases := []struct {
+		query string
+		err   string
+	}{
+		{"", "query is empty"},
+		{"count(*) FROM Transaction", `expected SELECT, FROM or SHOW at position 1, got "count"`},
+		{"SELECT count(*)) FROM Transaction", "unexpected ')' at position 16"},
+		{"SELECT count(*) FROM Transaction WHERE a = 1 WHERE b = 2", "WHERE clause at position 46 is repeated"},
+		{"SELECT count(*), FROM Transaction", `unexpected "," at position 16 at the end of the SELECT clause`},
+		{"SELECT count(*) FROM `Transaction", "unterminated quoted identifier at position 22"},
+		{"SELECT count(*) FROM Transaction; DROP", "unexpected character ';' at position 33"},
+	}
+
+	for _, c := range cases {
+		_, err := parseNrql(c.query)
+		if err == nil {
+			t.Fatalf("%s: expected error %q", c.query, c.err)
+		}
+
+		if err.Error() != c.err {
+			t.Fatalf("%s: expected error %q, got %q", c.query, c.err, err)
+		}
+	}
+}
+
+func TestNrqlAlertQuerySinceError(t *testing.T) {
+	cases := []struct {
+		query      string
+		sinceValue string
+		err        string
+	}{
+		{"SELECT count(*) FROM Transaction", "3", ""},
+		{"SELECT count(*) FROM Transaction SINCE 3 minutes ago", "3", `SINCE clause is not allowed in alert conditions, remove it as since_value is already "3"`},
+		{"SELECT count(*) FROM Transaction SINCE 2 minutes ago", "3", `SINCE 2 minutes ago does not match since_value = "3"; SINCE clause is not allowed in alert conditions, set since_value = "2" instead`},
+		{"SELECT count(*) FROM Transaction SINCE 2 minutes ago", "", `SINCE clause is not allowed in alert conditions, set since_value = "2" instead`},
+		{"SELECT count(*) FROM Transaction SINCE 1 hour ago", "3", "SINCE clause is not allowed in alert conditions, and since_value only supports 1 to 5 minutes"},
+	}
+
+	for _, c := range cases {
+		q, err := parseNrql(c.query)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.query, err)
+		}
+
+		err = nrqlAlertQuerySinceError(q, c.sinceValue)
+		if c.err == "" {
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %s", c.query, err)
+			}
+			continue
+		}
+
+		if err == nil || err.Error() != c.err {
+			t.Fatalf("%s: expected error %q, got %v", c.query, c.err, err)
+		}
+	}
+}
@@ -22,20 +22,41 @@ func Provider() terraform.ResourceProvider {
 				Optional:    true,
 				DefaultFunc: schema.EnvDefaultFunc("NEWRELIC_API_URL", "https://api.newrelic.com/v2"),
 			},
+			"insights_query_key": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("NEWRELIC_INSIGHTS_QUERY_KEY", nil),
+				Sensitive:   true,
+			},
+			"insights_url": {
+				Type:        schema.TypeString,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("NEWRELIC_INSIGHTS_URL", "https://insights-api.newrelic.com/v1"),
+			},
+			"account_id": {
+				Type:        schema.TypeInt,
+				Optional:    true,
+				DefaultFunc: schema.EnvDefaultFunc("NEWRELIC_ACCOUNT_ID", nil),
+			},
 		},
 
 		DataSourcesMap: map[string]*schema.Resource{
-			"newrelic_application":     dataSourceNewRelicApplication(),
-			"newrelic_key_transaction": dataSourceNewRelicKeyTransaction(),
+			"newrelic_application":                   dataSourceNewRelicApplication(),
+			"newrelic_insights_query":                dataSourceNewRelicInsightsQuery(),
+			"newrelic_key_transaction":               dataSourceNewRelicKeyTransaction(),
+			"newrelic_nrql_alert_condition_backtest": dataSourceNewRelicNrqlAlertConditionBacktest(),
 		},
 
 		ResourcesMap: map[string]*schema.Resource{
-			"newrelic_alert_channel":        resourceNewRelicAlertChannel(),
-			"newrelic_alert_condition":      resourceNewRelicAlertCondition(),
-			"newrelic_nrql_alert_condition": resourceNewRelicNrqlAlertCondition(),
-			"newrelic_alert_policy":         resourceNewRelicAlertPolicy(),
-			"newrelic_alert_policy_channel": resourceNewRelicAlertPolicyChannel(),
-			"newrelic_dashboard":            resourceNewRelicDashboard(),
+			"newrelic_alert_channel":                    resourceNewRelicAlertChannel(),
+			"newrelic_alert_condition":                  resourceNewRelicAlertCondition(),
+			"newrelic_alert_entity_condition":           resourceNewRelicAlertEntityCondition(),
+			"newrelic_alert_external_service_condition": resourceNewRelicAlertExternalServiceCondition(),
+			"newrelic_nrql_alert_condition":             resourceNewRelicNrqlAlertCondition(),
+			"newrelic_alert_policy":                     resourceNewRelicAlertPolicy(),
+			"newrelic_alert_policy_channel":             resourceNewRelicAlertPolicyChannel(),
+			"newrelic_alert_policy_channels":            resourceNewRelicAlertPolicyChannels(),
+			"newrelic_dashboard":                        resourceNewRelicDashboard(),
 		},
 
 		ConfigureFunc: providerConfigure,
@@ -44,9 +65,26 @@ func Provider() terraform.ResourceProvider {
 
 func providerConfigure(data *schema.ResourceData) (interface{}, error) {
 	config := Config{
-		APIKey: data.Get("api_key").(string),
-		APIURL: data.Get("api_url").(string),
+		APIKey:           data.Get("api_key").(string),
+		APIURL:           data.Get("api_url").(string),
+		InsightsQueryKey: data.Get("insights_query_key").(string),
+		InsightsURL:      data.Get("insights_url").(string),
+		AccountID:        data.Get("account_id").(int),
 	}
 	log.Println("[INFO] Initializing New Relic client")
-	return config.Client()
+
+	client, err := config.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	insightsClient, err := config.InsightsClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProviderConfig{
+		Client:         client,
+		InsightsClient: insightsClient,
+	}, nil
 }
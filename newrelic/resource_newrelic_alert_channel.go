@@ -1,58 +1,75 @@
 package newrelic
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
+	"reflect"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/structure"
 	"github.com/hashicorp/terraform/helper/validation"
 	newrelic "github.com/paultyng/go-newrelic/api"
 )
 
-var alertChannelTypes = map[string][]string{
-	"campfire": []string{
-		"room",
-		"subdomain",
-		"token",
+// alertChannelKey describes a single configuration key of an alert channel.
+// Redacted keys are secrets the API masks or omits when reading a channel.
+// JSON keys hold a JSON object and may also be given as a <key>_map block
+// attribute.
+type alertChannelKey struct {
+	required  bool
+	list      bool
+	sensitive bool
+	redacted  bool
+	json      bool
+}
+
+var alertChannelTypes = map[string]map[string]alertChannelKey{
+	"campfire": {
+		"room":      {required: true},
+		"subdomain": {required: true},
+		"token":     {required: true, sensitive: true, redacted: true},
 	},
-	"email": []string{
-		"include_json_attachment",
-		"recipients",
+	"email": {
+		"include_json_attachment": {},
+		"recipients":              {required: true, list: true},
 	},
-	"hipchat": []string{
-		"auth_token",
-		"base_url",
-		"room_id",
+	"hipchat": {
+		"auth_token": {required: true, sensitive: true, redacted: true},
+		"base_url":   {},
+		"room_id":    {required: true},
 	},
-	"opsgenie": []string{
-		"api_key",
-		"recipients",
-		"tags",
-		"teams",
+	"opsgenie": {
+		"api_key":    {required: true, sensitive: true, redacted: true},
+		"recipients": {list: true},
+		"tags":       {list: true},
+		"teams":      {list: true},
 	},
-	"pagerduty": []string{
-		"service_key",
+	"pagerduty": {
+		"service_key": {required: true, sensitive: true, redacted: true},
 	},
-	"slack": []string{
-		"channel",
-		"url",
+	"slack": {
+		"channel": {},
+		"url":     {required: true, sensitive: true, redacted: true},
 	},
-	"user": []string{
-		"user_id",
+	"user": {
+		"user_id": {required: true},
 	},
-	"victorops": []string{
-		"key",
-		"route_key",
+	"victorops": {
+		"key":       {required: true, sensitive: true, redacted: true},
+		"route_key": {required: true},
 	},
-	"webhook": []string{
-		"auth_password",
-		"auth_type",
-		"auth_username",
-		"base_url",
-		"headers",
-		"payload_type",
-		"payload",
+	"webhook": {
+		"auth_password": {sensitive: true, redacted: true},
+		"auth_type":     {},
+		"auth_username": {},
+		"base_url":      {required: true},
+		"headers":       {json: true},
+		"payload_type":  {},
+		"payload":       {json: true},
 	},
 }
 
@@ -61,66 +78,467 @@ func resourceNewRelicAlertChannel() *schema.Resource {
 	for k := range alertChannelTypes {
 		validAlertChannelTypes = append(validAlertChannelTypes, k)
 	}
+	sort.Strings(validAlertChannelTypes)
+
+	s := map[string]*schema.Schema{
+		"name": {
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"type": {
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			ValidateFunc: validation.StringInSlice(validAlertChannelTypes, false),
+		},
+		"configuration": {
+			Type:             schema.TypeMap,
+			Optional:         true,
+			ValidateFunc:     validateAlertChannelConfigurationKeys,
+			DiffSuppressFunc: suppressAlertChannelConfigurationSecrets,
+			Sensitive:        true,
+			ConflictsWith:    validAlertChannelTypes,
+		},
+		"policy_ids": {
+			Type:     schema.TypeSet,
+			Elem:     &schema.Schema{Type: schema.TypeInt},
+			Computed: true,
+		},
+	}
+
+	for _, channelType := range validAlertChannelTypes {
+		conflicts := []string{"configuration"}
+		for _, other := range validAlertChannelTypes {
+			if other != channelType {
+				conflicts = append(conflicts, other)
+			}
+		}
+
+		s[channelType] = alertChannelConfigurationSchema(channelType, conflicts)
+	}
 
 	return &schema.Resource{
 		Create: resourceNewRelicAlertChannelCreate,
 		Read:   resourceNewRelicAlertChannelRead,
-		// Update: Not currently supported in API
+		Update: resourceNewRelicAlertChannelUpdate,
 		Delete: resourceNewRelicAlertChannelDelete,
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
-		Schema: map[string]*schema.Schema{
-			"name": {
-				Type:     schema.TypeString,
-				Required: true,
-				ForceNew: true,
-			},
-			"type": {
-				Type:         schema.TypeString,
-				Required:     true,
-				ForceNew:     true,
-				ValidateFunc: validation.StringInSlice(validAlertChannelTypes, false),
-			},
-			"configuration": {
-				Type:     schema.TypeMap,
-				Required: true,
-				ForceNew: true,
-				//TODO: ValidateFunc: (use list of keys from map above)
-				Sensitive: true,
-			},
+		CustomizeDiff: resourceNewRelicAlertChannelCustomizeDiff,
+		Schema:        s,
+	}
+}
+
+// resourceNewRelicAlertChannelCustomizeDiff marks policy_ids as changing when
+// the channel is updated, so that the policies the replacement channel is
+// re-attached to show in the apply output.
+func resourceNewRelicAlertChannelCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if d.Id() == "" || len(d.GetChangedKeysPrefix("")) == 0 {
+		return nil
+	}
+
+	return d.SetNewComputed("policy_ids")
+}
+
+// alertChannelConfigurationSchema builds the typed configuration block for a
+// single channel type from its entry in alertChannelTypes.
+func alertChannelConfigurationSchema(channelType string, conflicts []string) *schema.Schema {
+	keys := alertChannelTypes[channelType]
+	elem := make(map[string]*schema.Schema, len(keys))
+
+	for name, key := range keys {
+		var attr *schema.Schema
+		if key.list {
+			attr = &schema.Schema{
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+				MinItems: 1,
+			}
+		} else {
+			attr = &schema.Schema{
+				Type: schema.TypeString,
+			}
+		}
+
+		attr.Required = key.required
+		attr.Optional = !key.required
+		attr.Sensitive = key.sensitive
+
+		if key.redacted {
+			attr.DiffSuppressFunc = suppressAlertChannelBlockSecrets(channelType)
+		}
+
+		if key.json {
+			attr.ValidateFunc = validateWebhookJSON
+			attr.DiffSuppressFunc = suppressAlertChannelJSONDiff
+			attr.ConflictsWith = []string{fmt.Sprintf("%s.0.%s_map", channelType, name)}
+
+			elem[name+"_map"] = &schema.Schema{
+				Type:          schema.TypeMap,
+				Optional:      true,
+				ValidateFunc:  validateWebhookMap,
+				ConflictsWith: []string{fmt.Sprintf("%s.0.%s", channelType, name)},
+			}
+		}
+
+		elem[name] = attr
+	}
+
+	return &schema.Schema{
+		Type:          schema.TypeList,
+		Optional:      true,
+		MaxItems:      1,
+		ConflictsWith: conflicts,
+		Elem: &schema.Resource{
+			Schema: elem,
 		},
 	}
 }
 
-func buildAlertChannelStruct(d *schema.ResourceData) *newrelic.AlertChannel {
+// alertChannelJSONEqual reports whether two JSON documents are semantically
+// equal, falling back to a plain comparison when either is not valid JSON.
+// Unquoted template variables are quoted first, as in validateWebhookJSON.
+func alertChannelJSONEqual(a string, b string) bool {
+	normalizedA, errA := structure.NormalizeJsonString(quoteWebhookTemplateVariables(a))
+	normalizedB, errB := structure.NormalizeJsonString(quoteWebhookTemplateVariables(b))
+	if errA != nil || errB != nil {
+		return a == b
+	}
+
+	return normalizedA == normalizedB
+}
+
+func suppressAlertChannelJSONDiff(k, old, new string, d *schema.ResourceData) bool {
+	return alertChannelJSONEqual(old, new)
+}
+
+// alertChannelSecretMasked reports whether a value read back from the API is
+// a placeholder for a redacted secret rather than the secret itself.
+func alertChannelSecretMasked(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+
+	s, ok := v.(string)
+	return ok && (s == "" || strings.Contains(s, "****"))
+}
+
+// mergeAlertChannelSecrets fills redacted keys missing from the remote
+// configuration with the values previously known in state.
+func mergeAlertChannelSecrets(channelType string, remote map[string]interface{}, prior map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(remote))
+	for k, v := range remote {
+		merged[k] = v
+	}
+
+	for name, key := range alertChannelTypes[channelType] {
+		if !key.redacted {
+			continue
+		}
+
+		if v, ok := merged[name]; ok && !alertChannelSecretMasked(v) {
+			continue
+		}
+
+		delete(merged, name)
+		if v, ok := prior[name]; ok && !alertChannelSecretMasked(v) {
+			merged[name] = v
+		}
+	}
+
+	return merged
+}
+
+// alertChannelOnlySecretsUnknown reports whether the old and new
+// configurations differ only in redacted keys whose old value is unknown,
+// e.g. right after an import.
+func alertChannelOnlySecretsUnknown(channelType string, old map[string]interface{}, new map[string]interface{}) bool {
+	keys := alertChannelTypes[channelType]
+
+	names := make(map[string]bool, len(old)+len(new))
+	for name := range old {
+		names[name] = true
+	}
+	for name := range new {
+		names[name] = true
+	}
+
+	for name := range names {
+		o, oldOk := old[name]
+		n, newOk := new[name]
+
+		if oldOk == newOk && reflect.DeepEqual(o, n) {
+			continue
+		}
+
+		if keys[name].json && oldOk && newOk && alertChannelJSONEqual(fmt.Sprintf("%v", o), fmt.Sprintf("%v", n)) {
+			continue
+		}
+
+		if keys[name].redacted && (!oldOk || alertChannelSecretMasked(o)) {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func suppressAlertChannelConfigurationSecrets(k, old, new string, d *schema.ResourceData) bool {
+	if d.Id() == "" {
+		return false
+	}
+
+	channelType := d.Get("type").(string)
+
+	parts := strings.Split(k, ".")
+	if key, ok := alertChannelTypes[channelType][parts[len(parts)-1]]; ok && key.json && alertChannelJSONEqual(old, new) {
+		return true
+	}
+
+	o, n := d.GetChange("configuration")
+
+	return alertChannelOnlySecretsUnknown(channelType, o.(map[string]interface{}), n.(map[string]interface{}))
+}
+
+func suppressAlertChannelBlockSecrets(channelType string) schema.SchemaDiffSuppressFunc {
+	return func(k, old, new string, d *schema.ResourceData) bool {
+		if d.Id() == "" {
+			return false
+		}
+
+		o, n := d.GetChange(channelType)
+
+		return alertChannelOnlySecretsUnknown(channelType, alertChannelBlockMap(o), alertChannelBlockMap(n))
+	}
+}
+
+func alertChannelBlockMap(v interface{}) map[string]interface{} {
+	blocks, _ := v.([]interface{})
+	if len(blocks) == 0 {
+		return map[string]interface{}{}
+	}
+
+	m, _ := blocks[0].(map[string]interface{})
+	if m == nil {
+		return map[string]interface{}{}
+	}
+
+	return m
+}
+
+func validateAlertChannelConfigurationKeys(v interface{}, k string) (ws []string, es []error) {
+	for key, value := range v.(map[string]interface{}) {
+		found, isJSON := false, false
+		for _, keys := range alertChannelTypes {
+			if channelKey, ok := keys[key]; ok {
+				found = true
+				isJSON = isJSON || channelKey.json
+			}
+		}
+
+		if !found {
+			es = append(es, fmt.Errorf("%s: unknown alert channel configuration key %q", k, key))
+		}
+
+		if isJSON {
+			_, errs := validateWebhookJSON(value, fmt.Sprintf("%s.%s", k, key))
+			es = append(es, errs...)
+		}
+	}
+
+	return
+}
+
+func expandAlertChannelConfiguration(channelType string, m map[string]interface{}) map[string]interface{} {
+	config := make(map[string]interface{})
+
+	for name, key := range alertChannelTypes[channelType] {
+		if key.json {
+			if jsonMap, ok := m[name+"_map"].(map[string]interface{}); ok && len(jsonMap) > 0 {
+				b, _ := json.Marshal(jsonMap)
+				config[name] = string(b)
+				continue
+			}
+		}
+
+		v, ok := m[name]
+		if !ok {
+			continue
+		}
+
+		if key.list {
+			raw := v.([]interface{})
+			values := make([]string, 0, len(raw))
+			for _, item := range raw {
+				values = append(values, item.(string))
+			}
+
+			if len(values) > 0 {
+				config[name] = strings.Join(values, ",")
+			}
+			continue
+		}
+
+		if s := v.(string); s != "" {
+			config[name] = s
+		}
+	}
+
+	return config
+}
+
+func flattenAlertChannelConfiguration(channelType string, config map[string]interface{}) map[string]interface{} {
+	m := make(map[string]interface{})
+
+	for name, key := range alertChannelTypes[channelType] {
+		v, ok := config[name]
+		if !ok || v == nil {
+			continue
+		}
+
+		s := fmt.Sprintf("%v", v)
+
+		if key.list {
+			var values []string
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+
+			m[name] = values
+			continue
+		}
+
+		m[name] = s
+	}
+
+	return m
+}
+
+// flattenAlertChannelJSONMaps moves JSON values into their <key>_map
+// attribute when that is how they were previously configured. A value holding
+// numbers, booleans or nested objects is left as a string, as the map only
+// holds strings.
+func flattenAlertChannelJSONMaps(channelType string, block map[string]interface{}, prior map[string]interface{}) map[string]interface{} {
+	for name, key := range alertChannelTypes[channelType] {
+		if !key.json {
+			continue
+		}
+
+		if jsonMap, ok := prior[name+"_map"].(map[string]interface{}); !ok || len(jsonMap) == 0 {
+			continue
+		}
+
+		raw, ok := block[name].(string)
+		if !ok {
+			continue
+		}
+
+		var decoded map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+			continue
+		}
+
+		m := make(map[string]interface{}, len(decoded))
+		for k, v := range decoded {
+			if s, ok := v.(string); ok {
+				m[k] = s
+			}
+		}
+
+		if len(m) != len(decoded) {
+			continue
+		}
+
+		delete(block, name)
+		block[name+"_map"] = m
+	}
+
+	return block
+}
+
+// alertChannelBlock returns the channel type whose configuration block is set.
+func alertChannelBlock(d *schema.ResourceData) (string, map[string]interface{}, bool) {
+	for channelType := range alertChannelTypes {
+		if v, ok := d.GetOk(channelType); ok {
+			return channelType, alertChannelBlockMap(v), true
+		}
+	}
+
+	return "", nil, false
+}
+
+func buildAlertChannelStruct(d *schema.ResourceData) (*newrelic.AlertChannel, error) {
 	channel := newrelic.AlertChannel{
-		Name:          d.Get("name").(string),
-		Type:          d.Get("type").(string),
-		Configuration: d.Get("configuration").(map[string]interface{}),
+		Name: d.Get("name").(string),
+		Type: d.Get("type").(string),
+	}
+
+	if channelType, m, ok := alertChannelBlock(d); ok {
+		if channel.Type != "" && channel.Type != channelType {
+			return nil, fmt.Errorf("type %q does not match the %s configuration block", channel.Type, channelType)
+		}
+
+		channel.Type = channelType
+		channel.Configuration = expandAlertChannelConfiguration(channelType, m)
+
+		return &channel, nil
+	}
+
+	if channel.Type == "" {
+		return nil, fmt.Errorf("type is required when using configuration")
 	}
 
-	return &channel
+	channel.Configuration = d.Get("configuration").(map[string]interface{})
+
+	keys := alertChannelTypes[channel.Type]
+	for key := range channel.Configuration {
+		if _, ok := keys[key]; !ok {
+			return nil, fmt.Errorf("configuration key %q is not valid for %s channels", key, channel.Type)
+		}
+	}
+
+	for name, key := range keys {
+		v, ok := channel.Configuration[name]
+		if key.required && !ok {
+			return nil, fmt.Errorf("configuration key %q is required for %s channels", name, channel.Type)
+		}
+
+		if key.json && ok {
+			if _, errs := validateWebhookJSON(v, "configuration."+name); len(errs) > 0 {
+				return nil, errs[0]
+			}
+		}
+	}
+
+	return &channel, nil
 }
 
 func resourceNewRelicAlertChannelCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	channel := buildAlertChannelStruct(d)
+	client := meta.(*ProviderConfig).Client
+	channel, err := buildAlertChannelStruct(d)
+	if err != nil {
+		return err
+	}
 
 	log.Printf("[INFO] Creating New Relic alert channel %s", channel.Name)
 
-	channel, err := client.CreateAlertChannel(*channel)
+	channel, err = client.CreateAlertChannel(*channel)
 	if err != nil {
 		return err
 	}
 
 	d.SetId(strconv.Itoa(channel.ID))
 
-	return nil
+	return resourceNewRelicAlertChannelRead(d, meta)
 }
 
 func resourceNewRelicAlertChannelRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	id, err := strconv.ParseInt(d.Id(), 10, 32)
 	if err != nil {
@@ -141,15 +559,99 @@ func resourceNewRelicAlertChannelRead(d *schema.ResourceData, meta interface{})
 
 	d.Set("name", channel.Name)
 	d.Set("type", channel.Type)
-	if err := d.Set("configuration", channel.Configuration); err != nil {
-		return fmt.Errorf("[DEBUG] Error setting Alert Channel Configuration: %#v", err)
+
+	if err := d.Set("policy_ids", channel.Links.PolicyIDs); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting Alert Channel policy IDs: %#v", err)
+	}
+
+	if _, ok := alertChannelTypes[channel.Type]; !ok {
+		if err := d.Set("configuration", channel.Configuration); err != nil {
+			return fmt.Errorf("[DEBUG] Error setting Alert Channel Configuration: %#v", err)
+		}
+	} else if v, ok := d.GetOk(channel.Type); ok || len(d.Get("configuration").(map[string]interface{})) == 0 {
+		// Without a prior block or configuration, as on import, the
+		// typed block is filled in.
+		block := flattenAlertChannelConfiguration(channel.Type, channel.Configuration)
+		prior := alertChannelBlockMap(v)
+		block = mergeAlertChannelSecrets(channel.Type, block, prior)
+		block = flattenAlertChannelJSONMaps(channel.Type, block, prior)
+		if err := d.Set(channel.Type, []interface{}{block}); err != nil {
+			return fmt.Errorf("[DEBUG] Error setting Alert Channel %s configuration: %#v", channel.Type, err)
+		}
+	} else {
+		prior := d.Get("configuration").(map[string]interface{})
+		config := mergeAlertChannelSecrets(channel.Type, channel.Configuration, prior)
+		if err := d.Set("configuration", config); err != nil {
+			return fmt.Errorf("[DEBUG] Error setting Alert Channel Configuration: %#v", err)
+		}
 	}
 
 	return nil
 }
 
+// The API cannot modify a channel, so an update creates the replacement
+// channel, attaches it to every policy the old channel was linked to and only
+// then deletes the old channel. Notifications keep flowing throughout. The
+// re-attached policies end up in policy_ids, which is marked as changing in the
+// plan by resourceNewRelicAlertChannelCustomizeDiff.
+func resourceNewRelicAlertChannelUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	oldID, err := strconv.ParseInt(d.Id(), 10, 32)
+	if err != nil {
+		return err
+	}
+
+	var policyIDs []int
+
+	old, err := client.GetAlertChannel(int(oldID))
+	if err != nil {
+		if err != newrelic.ErrNotFound {
+			return err
+		}
+	} else {
+		policyIDs = old.Links.PolicyIDs
+	}
+
+	channel, err := buildAlertChannelStruct(d)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Creating replacement for New Relic alert channel %v", oldID)
+
+	channel, err = client.CreateAlertChannel(*channel)
+	if err != nil {
+		return err
+	}
+
+	for _, policyID := range policyIDs {
+		if err := client.UpdateAlertPolicyChannels(policyID, []int{channel.ID}); err != nil {
+			if delErr := client.DeleteAlertChannel(channel.ID); delErr != nil {
+				log.Printf("[WARN] Unable to clean up New Relic alert channel %v: %s", channel.ID, delErr)
+			}
+
+			return fmt.Errorf("Error attaching alert channel %v to policy %v: %s", channel.ID, policyID, err)
+		}
+	}
+
+	if len(policyIDs) > 0 {
+		log.Printf("[INFO] Re-attached New Relic alert channel %v to policies %v", channel.ID, policyIDs)
+	}
+
+	d.SetId(strconv.Itoa(channel.ID))
+
+	log.Printf("[INFO] Deleting replaced New Relic alert channel %v", oldID)
+
+	if err := client.DeleteAlertChannel(int(oldID)); err != nil && err != newrelic.ErrNotFound {
+		return fmt.Errorf("Error deleting replaced alert channel %v: %s", oldID, err)
+	}
+
+	return resourceNewRelicAlertChannelRead(d, meta)
+}
+
 func resourceNewRelicAlertChannelDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	id, err := strconv.ParseInt(d.Id(), 10, 32)
 	if err != nil {
@@ -2,13 +2,14 @@ package newrelic
 
 import (
 	"fmt"
+	"reflect"
 	"strconv"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
-	newrelic "github.com/paultyng/go-newrelic/api"
 )
 
 func TestAccNewRelicAlertChannel_Basic(t *testing.T) {
@@ -50,8 +51,281 @@ func TestAccNewRelicAlertChannel_Basic(t *testing.T) {
 	})
 }
 
+func TestAccNewRelicAlertChannel_KeepsPolicyLinks(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertChannelDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertChannelConfigPolicy(rName, "foo@example.com"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertChannelExists("newrelic_alert_channel.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "policy_ids.#", "1"),
+					testAccNewRelicAlertChannelAttach("newrelic_alert_channel.foo", "newrelic_alert_policy.foo"),
+				),
+			},
+			// The replacement channel is attached to both policies, and the
+			// link to the replaced channel is planned again with the new ID.
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertChannelConfigPolicy(rName, "bar@example.com"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertChannelExists("newrelic_alert_channel.foo"),
+					testAccCheckNewRelicAlertChannelAttached("newrelic_alert_channel.foo", "newrelic_alert_policy.foo"),
+					testAccCheckNewRelicAlertChannelAttached("newrelic_alert_channel.foo", "newrelic_alert_policy.bar"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "configuration.recipients", "bar@example.com"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "policy_ids.#", "2"),
+				),
+				ExpectNonEmptyPlan: true,
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertChannelConfigPolicy(rName, "bar@example.com"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertChannelAttached("newrelic_alert_channel.foo", "newrelic_alert_policy.bar"),
+					resource.TestCheckResourceAttrPair(
+						"newrelic_alert_policy_channel.bar", "channel_id", "newrelic_alert_channel.foo", "id"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceNewRelicAlertChannelCustomizeDiff(t *testing.T) {
+	raw := func(recipients string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": "foo",
+			"type": "email",
+			"configuration": map[string]interface{}{
+				"recipients": recipients,
+			},
+		}
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNewRelicAlertChannel().Schema, raw("foo@example.com"))
+	d.SetId("1")
+	if err := d.Set("policy_ids", []int{1}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state := d.State()
+
+	diff, err := testResourceDiff(t, resourceNewRelicAlertChannel(), state, raw("foo@example.com"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if diff != nil {
+		t.Fatalf("expected no changes, got %#v", diff.Attributes)
+	}
+
+	diff, err = testResourceDiff(t, resourceNewRelicAlertChannel(), state, raw("bar@example.com"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attr := diff.Attributes["policy_ids.#"]; attr == nil || !attr.NewComputed {
+		t.Fatalf("expected computed policy_ids, got %#v", diff.Attributes)
+	}
+}
+
+func TestAlertChannelConfiguration_roundTrip(t *testing.T) {
+	block := map[string]interface{}{
+		"api_key":    "secret",
+		"recipients": []interface{}{"a@example.com", "b@example.com"},
+		"tags":       []interface{}{},
+		"teams":      []interface{}{"ops"},
+	}
+
+	config := expandAlertChannelConfiguration("opsgenie", block)
+	expected := map[string]interface{}{
+		"api_key":    "secret",
+		"recipients": "a@example.com,b@example.com",
+		"teams":      "ops",
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, config)
+	}
+
+	flattened := flattenAlertChannelConfiguration("opsgenie", map[string]interface{}{
+		"api_key":    "secret",
+		"recipients": "a@example.com, b@example.com",
+		"teams":      "ops",
+	})
+	expected = map[string]interface{}{
+		"api_key":    "secret",
+		"recipients": []string{"a@example.com", "b@example.com"},
+		"teams":      []string{"ops"},
+	}
+	if !reflect.DeepEqual(flattened, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, flattened)
+	}
+}
+
+func TestMergeAlertChannelSecrets(t *testing.T) {
+	remote := map[string]interface{}{
+		"channel": "#ops",
+	}
+	prior := map[string]interface{}{
+		"channel": "#old",
+		"url":     "https://hooks.slack.com/services/secret",
+	}
+
+	merged := mergeAlertChannelSecrets("slack", remote, prior)
+	expected := map[string]interface{}{
+		"channel": "#ops",
+		"url":     "https://hooks.slack.com/services/secret",
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, merged)
+	}
+
+	merged = mergeAlertChannelSecrets("pagerduty", map[string]interface{}{"service_key": "********"}, map[string]interface{}{})
+	if len(merged) != 0 {
+		t.Fatalf("expected masked secret to be dropped, got %#v", merged)
+	}
+}
+
+func TestAlertChannelOnlySecretsUnknown(t *testing.T) {
+	cases := []struct {
+		old      map[string]interface{}
+		new      map[string]interface{}
+		expected bool
+	}{
+		// imported: secret unknown in state
+		{
+			old:      map[string]interface{}{"channel": "#ops"},
+			new:      map[string]interface{}{"channel": "#ops", "url": "https://example.com"},
+			expected: true,
+		},
+		// secret known and changed
+		{
+			old:      map[string]interface{}{"channel": "#ops", "url": "https://example.com/a"},
+			new:      map[string]interface{}{"channel": "#ops", "url": "https://example.com/b"},
+			expected: false,
+		},
+		// non-secret changed
+		{
+			old:      map[string]interface{}{"channel": "#ops"},
+			new:      map[string]interface{}{"channel": "#dev", "url": "https://example.com"},
+			expected: false,
+		},
+	}
+
+	for i, tc := range cases {
+		if actual := alertChannelOnlySecretsUnknown("slack", tc.old, tc.new); actual != tc.expected {
+			t.Fatalf("case %d: expected %t, got %t", i, tc.expected, actual)
+		}
+	}
+}
+
+func TestAlertChannelJSONEqual(t *testing.T) {
+	if !alertChannelJSONEqual(`{"a": 1, "b": "$POLICY_NAME"}`, `{"b":"$POLICY_NAME","a":1}`) {
+		t.Fatal("expected reordered JSON to be equal")
+	}
+
+	if alertChannelJSONEqual(`{"a": 1}`, `{"a": 2}`) {
+		t.Fatal("expected different JSON not to be equal")
+	}
+
+	if !alertChannelJSONEqual(`{"a": 1, "count": $OPEN_VIOLATIONS_COUNT_CRITICAL}`, `{"count":$OPEN_VIOLATIONS_COUNT_CRITICAL,"a":1}`) {
+		t.Fatal("expected reordered JSON with unquoted variables to be equal")
+	}
+
+	if alertChannelJSONEqual(`{"count": $OPEN_VIOLATIONS_COUNT_CRITICAL}`, `{"count": $OPEN_VIOLATIONS_COUNT_WARNING}`) {
+		t.Fatal("expected different unquoted variables not to be equal")
+	}
+
+	if alertChannelJSONEqual(`{"count": $OPEN_VIOLATIONS_COUNT_CRITICAL}`, `{"count": "$OPEN_VIOLATIONS_COUNT_CRITICAL"}`) {
+		t.Fatal("expected unquoted and quoted variables not to be equal")
+	}
+}
+
+func TestFlattenAlertChannelJSONMaps(t *testing.T) {
+	block := map[string]interface{}{
+		"base_url": "https://example.com",
+		"headers":  `{"X-Policy":"$POLICY_NAME"}`,
+		"payload":  `{"id":"$INCIDENT_ID"}`,
+	}
+	prior := map[string]interface{}{
+		"headers_map": map[string]interface{}{"X-Policy": "$POLICY_NAME"},
+	}
+
+	flattened := flattenAlertChannelJSONMaps("webhook", block, prior)
+	expected := map[string]interface{}{
+		"base_url":    "https://example.com",
+		"headers_map": map[string]interface{}{"X-Policy": "$POLICY_NAME"},
+		"payload":     `{"id":"$INCIDENT_ID"}`,
+	}
+	if !reflect.DeepEqual(flattened, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, flattened)
+	}
+}
+
+func TestFlattenAlertChannelJSONMapsNonString(t *testing.T) {
+	block := map[string]interface{}{
+		"payload": `{"id":"$INCIDENT_ID","count":$OPEN_VIOLATIONS_COUNT_CRITICAL}`,
+	}
+	prior := map[string]interface{}{
+		"payload_map": map[string]interface{}{"id": "$INCIDENT_ID"},
+	}
+
+	flattened := flattenAlertChannelJSONMaps("webhook", block, prior)
+	expected := map[string]interface{}{
+		"payload": `{"id":"$INCIDENT_ID","count":$OPEN_VIOLATIONS_COUNT_CRITICAL}`,
+	}
+	if !reflect.DeepEqual(flattened, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, flattened)
+	}
+}
+
+func TestValidateAlertChannelConfigurationKeys(t *testing.T) {
+	_, errs := validateAlertChannelConfigurationKeys(map[string]interface{}{"recipients": "foo@example.com"}, "configuration")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	_, errs = validateAlertChannelConfigurationKeys(map[string]interface{}{"recipient": "foo@example.com"}, "configuration")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+
+	_, errs = validateAlertChannelConfigurationKeys(map[string]interface{}{"payload": `{"id": $INCIDENT_ID}`}, "configuration")
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	_, errs = validateAlertChannelConfigurationKeys(map[string]interface{}{"headers": `{"X-Policy": "$POLICY_NAME"`}, "configuration")
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+}
+
+func TestAccNewRelicAlertChannel_TypedBlock(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertChannelDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertChannelConfigTypedBlock(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertChannelExists("newrelic_alert_channel.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "type", "email"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "email.0.recipients.#", "2"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_channel.foo", "email.0.recipients.1", "bar@example.com"),
+				),
+			},
+		},
+	})
+}
+
 func testAccCheckNewRelicAlertChannelDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*newrelic.Client)
+	client := testAccProvider.Meta().(*ProviderConfig).Client
 	for _, r := range s.RootModule().Resources {
 		if r.Type != "newrelic_alert_channel" {
 			continue
@@ -82,7 +356,7 @@ func testAccCheckNewRelicAlertChannelExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("No channel ID is set")
 		}
 
-		client := testAccProvider.Meta().(*newrelic.Client)
+		client := testAccProvider.Meta().(*ProviderConfig).Client
 
 		id, err := strconv.ParseInt(rs.Primary.ID, 10, 32)
 		if err != nil {
@@ -102,6 +376,59 @@ func testAccCheckNewRelicAlertChannelExists(n string) resource.TestCheckFunc {
 	}
 }
 
+// testAccNewRelicAlertChannelAttach links the channel to the policy outside
+// of Terraform, as links made in the UI would be.
+func testAccNewRelicAlertChannelAttach(channel string, policy string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		channelID, err := strconv.Atoi(s.RootModule().Resources[channel].Primary.ID)
+		if err != nil {
+			return err
+		}
+		policyID, err := strconv.Atoi(s.RootModule().Resources[policy].Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		return client.UpdateAlertPolicyChannels(policyID, []int{channelID})
+	}
+}
+
+func testAccCheckNewRelicAlertChannelAttached(channel string, policy string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		crs, ok := s.RootModule().Resources[channel]
+		if !ok {
+			return fmt.Errorf("Not found: %s", channel)
+		}
+		prs, ok := s.RootModule().Resources[policy]
+		if !ok {
+			return fmt.Errorf("Not found: %s", policy)
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		channelID, err := strconv.Atoi(crs.Primary.ID)
+		if err != nil {
+			return err
+		}
+		policyID, err := strconv.Atoi(prs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		exists, err := policyChannelExists(client, policyID, channelID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("Channel %d is not attached to policy %d", channelID, policyID)
+		}
+
+		return nil
+	}
+}
+
 func testAccCheckNewRelicAlertChannelConfig(rName string) string {
 	return fmt.Sprintf(`
 resource "newrelic_alert_channel" "foo" {
@@ -129,3 +456,43 @@ resource "newrelic_alert_channel" "foo" {
 }
 `, rName)
 }
+
+func testAccCheckNewRelicAlertChannelConfigPolicy(rName string, recipients string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_policy" "bar" {
+  name = "tf-test-bar-%[1]s"
+}
+
+resource "newrelic_alert_channel" "foo" {
+  name = "tf-test-%[1]s"
+  type = "email"
+
+  configuration = {
+    recipients              = "%[2]s"
+    include_json_attachment = "1"
+  }
+}
+
+resource "newrelic_alert_policy_channel" "bar" {
+  policy_id  = "${newrelic_alert_policy.bar.id}"
+  channel_id = "${newrelic_alert_channel.foo.id}"
+}
+`, rName, recipients)
+}
+
+func testAccCheckNewRelicAlertChannelConfigTypedBlock(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_channel" "foo" {
+  name = "tf-test-%s"
+
+  email {
+    recipients              = ["foo@example.com", "bar@example.com"]
+    include_json_attachment = "1"
+  }
+}
+`, rName)
+}
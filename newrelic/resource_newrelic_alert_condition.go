@@ -3,8 +3,11 @@ package newrelic
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
+	"strings"
 
+	"github.com/hashicorp/terraform/helper/hashcode"
 	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/helper/validation"
 	newrelic "github.com/paultyng/go-newrelic/api"
@@ -68,6 +71,639 @@ var alertConditionTypes = map[string][]string{
 	},
 }
 
+var alertConditionDurations = []int{5, 10, 15, 30, 60, 120}
+
+// alertConditionInstanceScopeTypes are the condition types that can be
+// evaluated per instance rather than per application.
+var alertConditionInstanceScopeTypes = []string{
+	"apm_app_metric",
+	"apm_jvm_metric",
+}
+
+func stringInSlice(v string, valid []string) bool {
+	for _, s := range valid {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// alertConditionMetricQuantities maps metrics to the quantity their
+// thresholds measure. Thresholds of other metrics are plain numbers.
+var alertConditionMetricQuantities = map[string]string{
+	"ajax_response_time":       "time",
+	"database":                 "time",
+	"dom_processing":           "time",
+	"images":                   "time",
+	"json":                     "time",
+	"network":                  "time",
+	"page_rendering":           "time",
+	"request_queuing":          "time",
+	"response_time":            "time",
+	"response_time_background": "time",
+	"response_time_web":        "time",
+	"total_page_load":          "time",
+	"view_loading":             "time",
+	"web_application":          "time",
+
+	"cpu_percentage":            "percent",
+	"cpu_utilization_time":      "percent",
+	"disk_io_percentage":        "percent",
+	"error_percentage":          "percent",
+	"fullest_disk_percentage":   "percent",
+	"gc_cpu_time":               "percent",
+	"heap_memory_usage":         "percent",
+	"memory_percentage":         "percent",
+	"mobile_crash_rate":         "percent",
+	"network_error_percentage":  "percent",
+	"page_views_with_js_errors": "percent",
+	"status_error_percentage":   "percent",
+
+	"response_time_average":         "time",
+	"response_time_minimum_maximum": "time",
+
+	"ajax_throughput":       "throughput",
+	"page_view_throughput":  "throughput",
+	"throughput":            "throughput",
+	"throughput_background": "throughput",
+	"throughput_web":        "throughput",
+}
+
+func formatAlertConditionThreshold(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// suppressAlertConditionThresholdDiff ignores differences in how the same
+// threshold is written, such as "500ms" and "0.5".
+func suppressAlertConditionThresholdDiff(k, old, new string, d *schema.ResourceData) bool {
+	o, _, err := parseAlertConditionThreshold(old)
+	if err != nil {
+		return false
+	}
+
+	n, _, err := parseAlertConditionThreshold(new)
+	if err != nil {
+		return false
+	}
+
+	return o == n
+}
+
+// suppressAlertConditionDurationDiff ignores differences in how the same
+// duration is written, such as "1h" and "60".
+func suppressAlertConditionDurationDiff(k, old, new string, d *schema.ResourceData) bool {
+	o, err := parseAlertConditionDuration(old)
+	if err != nil {
+		return false
+	}
+
+	n, err := parseAlertConditionDuration(new)
+	if err != nil {
+		return false
+	}
+
+	return o == n
+}
+
+// alertConditionDurationSchema returns the schema of a term duration, in
+// minutes, which must be one of durations.
+func alertConditionDurationSchema(durations []int) *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeString,
+		Required:         true,
+		ValidateFunc:     validateAlertConditionDuration(durations),
+		DiffSuppressFunc: suppressAlertConditionDurationDiff,
+	}
+}
+
+// alertConditionThresholdValueSchema returns the schema of a term threshold,
+// which may carry a unit.
+func alertConditionThresholdValueSchema() *schema.Schema {
+	return &schema.Schema{
+		Type:             schema.TypeString,
+		Required:         true,
+		ValidateFunc:     validateAlertConditionThreshold,
+		DiffSuppressFunc: suppressAlertConditionThresholdDiff,
+	}
+}
+
+// alertConditionThresholdSchema returns the schema of the critical and
+// warning blocks, an alternative to term lists for condition resources.
+func alertConditionThresholdSchema(durations []int) *schema.Schema {
+	return &schema.Schema{
+		Type:          schema.TypeList,
+		Optional:      true,
+		MaxItems:      1,
+		ConflictsWith: []string{"term"},
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"duration": alertConditionDurationSchema(durations),
+				"operator": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "equal",
+					ValidateFunc: validation.StringInSlice([]string{"above", "below", "equal"}, false),
+				},
+				"threshold": alertConditionThresholdValueSchema(),
+				"time_function": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"all", "any"}, false),
+				},
+			},
+		},
+	}
+}
+
+// expandAlertConditionTerm converts a term or threshold block, converting a
+// threshold given with a unit to the unit the API uses.
+func expandAlertConditionTerm(termM map[string]interface{}, priority string) (newrelic.AlertConditionTerm, error) {
+	term := newrelic.AlertConditionTerm{
+		Operator:     termM["operator"].(string),
+		Priority:     priority,
+		TimeFunction: termM["time_function"].(string),
+	}
+
+	duration, err := parseAlertConditionDuration(termM["duration"].(string))
+	if err != nil {
+		return term, err
+	}
+	term.Duration = duration
+
+	threshold, _, err := parseAlertConditionThreshold(termM["threshold"].(string))
+	if err != nil {
+		return term, err
+	}
+	term.Threshold = threshold
+
+	return term, nil
+}
+
+// validateAlertConditionThresholdUnit checks that the unit of a threshold, if
+// any, fits the metric. metric is empty for conditions without a metric,
+// which only accept plain thresholds.
+func validateAlertConditionThresholdUnit(threshold string, metric string) error {
+	_, quantity, err := parseAlertConditionThreshold(threshold)
+	if err != nil {
+		return err
+	}
+
+	if quantity == "" {
+		return nil
+	}
+
+	if metric == "" {
+		return fmt.Errorf("threshold %q: units are only supported for metric conditions", threshold)
+	}
+
+	if expected := alertConditionMetricQuantities[metric]; quantity != expected {
+		if expected == "" {
+			return fmt.Errorf("threshold %q: metric %s has no unit, use a plain number", threshold, metric)
+		}
+
+		return fmt.Errorf("threshold %q measures %s, but metric %s measures %s", threshold, quantity, metric, expected)
+	}
+
+	return nil
+}
+
+// alertConditionTermGetter is implemented by both *schema.ResourceData and
+// *schema.ResourceDiff, so that terms can be expanded when planning as well as
+// when applying.
+type alertConditionTermGetter interface {
+	Get(key string) interface{}
+}
+
+// expandAlertConditionTerms returns the terms configured either as a term
+// list or as critical and warning blocks.
+func expandAlertConditionTerms(d alertConditionTermGetter) ([]newrelic.AlertConditionTerm, error) {
+	var terms []newrelic.AlertConditionTerm
+
+	var termList []interface{}
+	switch v := d.Get("term").(type) {
+	case *schema.Set:
+		termList = v.List()
+	case []interface{}:
+		termList = v
+	}
+
+	if len(termList) > 0 {
+		for _, termI := range termList {
+			termM := termI.(map[string]interface{})
+
+			term, err := expandAlertConditionTerm(termM, termM["priority"].(string))
+			if err != nil {
+				return nil, err
+			}
+
+			terms = append(terms, term)
+		}
+	} else {
+		for _, priority := range []string{"critical", "warning"} {
+			blocks := d.Get(priority).([]interface{})
+			if len(blocks) == 0 {
+				continue
+			}
+
+			term, err := expandAlertConditionTerm(blocks[0].(map[string]interface{}), priority)
+			if err != nil {
+				return nil, err
+			}
+
+			terms = append(terms, term)
+		}
+	}
+
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("one of term or critical must be given")
+	}
+
+	return terms, nil
+}
+
+// alertConditionTermBlocks returns the configured term list and critical and
+// warning blocks together.
+func alertConditionTermBlocks(d alertConditionTermGetter) []interface{} {
+	blocks := append(d.Get("critical").([]interface{}), d.Get("warning").([]interface{})...)
+
+	switch v := d.Get("term").(type) {
+	case *schema.Set:
+		blocks = append(blocks, v.List()...)
+	case []interface{}:
+		blocks = append(blocks, v...)
+	}
+
+	return blocks
+}
+
+// alertConditionTermsKnown reports whether every planned term is known.
+func alertConditionTermsKnown(d *schema.ResourceDiff) bool {
+	for _, k := range []string{"term", "critical", "warning"} {
+		if !d.NewValueKnown(k) {
+			log.Printf("[DEBUG] New Relic alert condition %s is not known yet", k)
+			return false
+		}
+	}
+
+	// Every term field is required or has a default, so an empty one holds a
+	// value that is not known until apply.
+	for _, block := range alertConditionTermBlocks(d) {
+		for k, v := range block.(map[string]interface{}) {
+			if v == "" {
+				log.Printf("[DEBUG] New Relic alert condition term %s is not known yet", k)
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// validateAlertConditionTermsDiff checks the planned terms of a condition
+// resource once they are known. metric is as for
+// validateAlertConditionThresholdUnit.
+func validateAlertConditionTermsDiff(d *schema.ResourceDiff, metric string) error {
+	if !alertConditionTermsKnown(d) {
+		return nil
+	}
+
+	for _, block := range alertConditionTermBlocks(d) {
+		threshold := block.(map[string]interface{})["threshold"].(string)
+		if err := validateAlertConditionThresholdUnit(threshold, metric); err != nil {
+			return err
+		}
+	}
+
+	terms, err := expandAlertConditionTerms(d)
+	if err != nil {
+		return err
+	}
+
+	return validateAlertConditionTerms(terms)
+}
+
+// flattenAlertConditionTerms sets the terms in the form used by the
+// configuration, defaulting to the term list, e.g. after an import.
+func flattenAlertConditionTerms(d *schema.ResourceData, terms []newrelic.AlertConditionTerm) error {
+	if _, ok := d.GetOk("critical"); ok {
+		for _, priority := range []string{"critical", "warning"} {
+			var blocks []map[string]interface{}
+
+			for _, src := range terms {
+				if src.Priority != priority {
+					continue
+				}
+
+				blocks = append(blocks, map[string]interface{}{
+					"duration":      strconv.Itoa(src.Duration),
+					"operator":      src.Operator,
+					"threshold":     formatAlertConditionThreshold(src.Threshold),
+					"time_function": src.TimeFunction,
+				})
+			}
+
+			if err := d.Set(priority, blocks); err != nil {
+				return fmt.Errorf("[DEBUG] Error setting alert condition %s term: %#v", priority, err)
+			}
+		}
+
+		return nil
+	}
+
+	var termList []map[string]interface{}
+
+	for _, src := range terms {
+		dst := map[string]interface{}{
+			"duration":      strconv.Itoa(src.Duration),
+			"operator":      src.Operator,
+			"priority":      src.Priority,
+			"threshold":     formatAlertConditionThreshold(src.Threshold),
+			"time_function": src.TimeFunction,
+		}
+		termList = append(termList, dst)
+	}
+
+	if err := d.Set("term", termList); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting alert condition terms: %#v", err)
+	}
+
+	return nil
+}
+
+// validateAlertConditionTerms checks that there is exactly one critical term
+// and that a warning term triggers before it.
+func validateAlertConditionTerms(terms []newrelic.AlertConditionTerm) error {
+	var critical, warning *newrelic.AlertConditionTerm
+
+	for i := range terms {
+		term := &terms[i]
+
+		switch term.Priority {
+		case "critical":
+			if critical != nil {
+				return fmt.Errorf("only one critical term is allowed")
+			}
+			critical = term
+		case "warning":
+			if warning != nil {
+				return fmt.Errorf("only one warning term is allowed")
+			}
+			warning = term
+		}
+	}
+
+	if critical == nil {
+		return fmt.Errorf("a critical term is required")
+	}
+
+	if warning == nil {
+		return nil
+	}
+
+	if warning.Operator != critical.Operator {
+		return fmt.Errorf("warning operator %q must match critical operator %q", warning.Operator, critical.Operator)
+	}
+
+	if warning.Duration != critical.Duration {
+		return fmt.Errorf("warning duration %d must match critical duration %d", warning.Duration, critical.Duration)
+	}
+
+	switch critical.Operator {
+	case "above":
+		if warning.Threshold >= critical.Threshold {
+			return fmt.Errorf("warning threshold %v must be below critical threshold %v for operator \"above\"", warning.Threshold, critical.Threshold)
+		}
+	case "below":
+		if warning.Threshold <= critical.Threshold {
+			return fmt.Errorf("warning threshold %v must be above critical threshold %v for operator \"below\"", warning.Threshold, critical.Threshold)
+		}
+	case "equal":
+		if warning.Threshold == critical.Threshold {
+			return fmt.Errorf("warning threshold %v must differ from critical threshold %v for operator \"equal\"", warning.Threshold, critical.Threshold)
+		}
+	}
+
+	return nil
+}
+
+// validateAlertConditionMetric rejects metrics that are not valid for any
+// condition type. Whether the metric matches the configured type is checked
+// by validateAlertCondition.
+func validateAlertConditionMetric(v interface{}, k string) (ws []string, es []error) {
+	metric := v.(string)
+
+	var all []string
+	for _, metrics := range alertConditionTypes {
+		for _, m := range metrics {
+			if !stringInSlice(m, all) {
+				all = append(all, m)
+			}
+		}
+	}
+
+	if !stringInSlice(metric, all) {
+		sort.Strings(all)
+		es = append(es, fmt.Errorf("%s: unknown metric %q, expected one of: %s", k, metric, strings.Join(all, ", ")))
+	}
+
+	return
+}
+
+// validateAlertCondition checks the fields whose valid values depend on the
+// condition type or metric.
+func validateAlertCondition(condition *newrelic.AlertCondition) error {
+	metrics, ok := alertConditionTypes[condition.Type]
+	if !ok {
+		return fmt.Errorf("unknown alert condition type %q", condition.Type)
+	}
+
+	if !stringInSlice(condition.Metric, metrics) {
+		return fmt.Errorf("metric %q is not valid for %s conditions, expected one of: %s", condition.Metric, condition.Type, strings.Join(metrics, ", "))
+	}
+
+	if condition.GCMetric != "" && condition.Type != "apm_jvm_metric" {
+		return fmt.Errorf("gc_metric is only valid for apm_jvm_metric conditions")
+	}
+
+	if condition.Metric == "gc_cpu_time" && condition.GCMetric == "" {
+		return fmt.Errorf("gc_metric is required for the gc_cpu_time metric")
+	}
+
+	userDefined := condition.UserDefined.Metric != "" || condition.UserDefined.ValueFunction != ""
+	if userDefined && condition.Metric != "user_defined" {
+		return fmt.Errorf("user_defined_metric and user_defined_value_function require metric = \"user_defined\"")
+	}
+
+	if condition.Metric == "user_defined" && (condition.UserDefined.Metric == "" || condition.UserDefined.ValueFunction == "") {
+		return fmt.Errorf("metric \"user_defined\" requires both user_defined_metric and user_defined_value_function")
+	}
+
+	if condition.Scope == "instance" && !stringInSlice(condition.Type, alertConditionInstanceScopeTypes) {
+		return fmt.Errorf("condition_scope \"instance\" is not valid for %s conditions, expected one of: %s", condition.Type, strings.Join(alertConditionInstanceScopeTypes, ", "))
+	}
+
+	return nil
+}
+
+type alertConditionEntity struct {
+	ID   int
+	Name string
+}
+
+// listAlertConditionEntities lists the entities a condition of the given type
+// can target, using the list endpoint matching the entity kind.
+func listAlertConditionEntities(client *newrelic.Client, conditionType string) ([]alertConditionEntity, error) {
+	var entities []alertConditionEntity
+
+	switch conditionType {
+	case "apm_app_metric", "apm_jvm_metric":
+		applications, err := client.ListApplications()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range applications {
+			entities = append(entities, alertConditionEntity{a.ID, a.Name})
+		}
+	case "apm_kt_metric":
+		transactions, err := client.ListKeyTransactions()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range transactions {
+			entities = append(entities, alertConditionEntity{t.ID, t.Name})
+		}
+	case "browser_metric":
+		applications, err := client.ListBrowserApplications()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range applications {
+			entities = append(entities, alertConditionEntity{a.ID, a.Name})
+		}
+	case "mobile_metric":
+		applications, err := client.ListMobileApplications()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range applications {
+			entities = append(entities, alertConditionEntity{a.ID, a.Name})
+		}
+	case "servers_metric":
+		servers, err := client.ListServers()
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range servers {
+			entities = append(entities, alertConditionEntity{s.ID, s.Name})
+		}
+	default:
+		return nil, fmt.Errorf("entity_names is not supported for %s conditions", conditionType)
+	}
+
+	return entities, nil
+}
+
+// resolveAlertConditionEntityNames maps each name to the ID of the single
+// entity with that name.
+func resolveAlertConditionEntityNames(conditionType string, entities []alertConditionEntity, names []string) ([]int, error) {
+	ids := make([]int, len(names))
+
+	for i, name := range names {
+		var matches []int
+		for _, entity := range entities {
+			if entity.Name == name {
+				matches = append(matches, entity.ID)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			return nil, fmt.Errorf("The name '%s' does not match any entity for %s conditions.", name, conditionType)
+		case 1:
+			ids[i] = matches[0]
+		default:
+			return nil, fmt.Errorf("The name '%s' matches %d entities for %s conditions: %v", name, len(matches), conditionType, matches)
+		}
+	}
+
+	return ids, nil
+}
+
+// alertConditionEntityNames maps entity IDs back to names. IDs without a
+// matching entity are reported as the ID itself so they still show as drift.
+func alertConditionEntityNames(entities []alertConditionEntity, ids []string) []string {
+	names := make([]string, len(ids))
+
+	for i, id := range ids {
+		names[i] = id
+		for _, entity := range entities {
+			if strconv.Itoa(entity.ID) == id {
+				names[i] = entity.Name
+				break
+			}
+		}
+	}
+
+	return names
+}
+
+// resolveAlertConditionLabelSelector returns the IDs of the entities carrying
+// every label in the selector, which maps label categories to names.
+func resolveAlertConditionLabelSelector(conditionType string, labels []newrelic.Label, selector map[string]interface{}) ([]int, error) {
+	servers := false
+
+	switch conditionType {
+	case "apm_app_metric", "apm_jvm_metric":
+	case "servers_metric":
+		servers = true
+	default:
+		return nil, fmt.Errorf("entity_label_selector is not supported for %s conditions", conditionType)
+	}
+
+	var ids []int
+
+	first := true
+	for category, name := range selector {
+		key := fmt.Sprintf("%s:%s", category, name.(string))
+
+		var linked []int
+		for _, label := range labels {
+			if label.Key != key {
+				continue
+			}
+
+			if servers {
+				linked = label.Links.Servers
+			} else {
+				linked = label.Links.Applications
+			}
+		}
+
+		if first {
+			ids = append(ids, linked...)
+			first = false
+			continue
+		}
+
+		var matched []int
+		for _, id := range ids {
+			for _, l := range linked {
+				if id == l {
+					matched = append(matched, id)
+					break
+				}
+			}
+		}
+		ids = matched
+	}
+
+	sort.Ints(ids)
+
+	return ids, nil
+}
+
 func resourceNewRelicAlertCondition() *schema.Resource {
 	validAlertConditionTypes := make([]string, 0, len(alertConditionTypes))
 	for k := range alertConditionTypes {
@@ -82,6 +718,9 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		SchemaVersion: 1,
+		MigrateState:  resourceNewRelicAlertConditionMigrateState,
+		CustomizeDiff: resourceNewRelicAlertConditionCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"policy_id": {
 				Type:     schema.TypeInt,
@@ -98,23 +737,48 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 				ValidateFunc: validation.StringInSlice(validAlertConditionTypes, false),
 			},
 			"entities": {
-				Type:     schema.TypeList,
-				Elem:     &schema.Schema{Type: schema.TypeInt},
-				Required: true,
-				MinItems: 1,
+				Type:          schema.TypeSet,
+				Elem:          &schema.Schema{Type: schema.TypeInt},
+				Optional:      true,
+				Computed:      true,
+				MinItems:      1,
+				ConflictsWith: []string{"entity_names", "entity_label_selector"},
+			},
+			"entity_names": {
+				Type:          schema.TypeSet,
+				Elem:          &schema.Schema{Type: schema.TypeString},
+				Optional:      true,
+				MinItems:      1,
+				ConflictsWith: []string{"entities", "entity_label_selector"},
+			},
+			"entity_label_selector": {
+				Type:          schema.TypeMap,
+				Optional:      true,
+				ConflictsWith: []string{"entities", "entity_names"},
+			},
+			"enabled": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"ignore_remote_entities": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
 			},
 			"metric": {
-				Type:     schema.TypeString,
-				Required: true,
-				//TODO: ValidateFunc from map
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validateAlertConditionMetric,
 			},
 			"runbook_url": {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
 			"condition_scope": {
-				Type:     schema.TypeString,
-				Optional: true,
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"application", "instance"}, false),
 			},
 			"violation_close_timer": {
 				Type:         schema.TypeInt,
@@ -126,14 +790,11 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 				Optional: true,
 			},
 			"term": {
-				Type: schema.TypeList,
+				Type: schema.TypeSet,
+				Set:  resourceNewRelicAlertConditionTermHash,
 				Elem: &schema.Resource{
 					Schema: map[string]*schema.Schema{
-						"duration": {
-							Type:         schema.TypeInt,
-							Required:     true,
-							ValidateFunc: intInSlice([]int{5, 10, 15, 30, 60, 120}),
-						},
+						"duration": alertConditionDurationSchema(alertConditionDurations),
 						"operator": {
 							Type:         schema.TypeString,
 							Optional:     true,
@@ -146,11 +807,7 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 							Default:      "critical",
 							ValidateFunc: validation.StringInSlice([]string{"critical", "warning"}, false),
 						},
-						"threshold": {
-							Type:         schema.TypeFloat,
-							Required:     true,
-							ValidateFunc: float64Gte(0.0),
-						},
+						"threshold": alertConditionThresholdValueSchema(),
 						"time_function": {
 							Type:         schema.TypeString,
 							Required:     true,
@@ -158,9 +815,12 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 						},
 					},
 				},
-				Required: true,
-				MinItems: 1,
+				Optional:      true,
+				MinItems:      1,
+				ConflictsWith: []string{"critical", "warning"},
 			},
+			"critical": alertConditionThresholdSchema(alertConditionDurations),
+			"warning":  alertConditionThresholdSchema(alertConditionDurations),
 			"user_defined_metric": {
 				Type:     schema.TypeString,
 				Optional: true,
@@ -174,33 +834,119 @@ func resourceNewRelicAlertCondition() *schema.Resource {
 	}
 }
 
-func buildAlertConditionStruct(d *schema.ResourceData) *newrelic.AlertCondition {
-	entitySet := d.Get("entities").([]interface{})
-	entities := make([]string, len(entitySet))
+// resourceNewRelicAlertConditionCustomizeDiff rejects combinations of fields
+// that are valid on their own but not together, and resolves entity_names and
+// entity_label_selector into entities, so that mistakes fail at plan time and
+// the plan shows the entities the condition will target.
+func resourceNewRelicAlertConditionCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if err := validateAlertConditionDiff(d); err != nil {
+		return err
+	}
 
-	for i, entity := range entitySet {
-		entities[i] = strconv.Itoa(entity.(int))
+	if err := resolveAlertConditionEntityNamesDiff(d, meta); err != nil {
+		return err
+	}
+
+	if err := resolveAlertConditionLabelSelectorDiff(d, meta); err != nil {
+		return err
+	}
+
+	return validateAlertConditionEntitiesDiff(d)
+}
+
+// validateAlertConditionEntitiesDiff requires entities, either given or
+// resolved from entity_names or entity_label_selector, unless they are
+// attached with newrelic_alert_entity_condition. An entities set that is not
+// configured plans the same as one that is not known until apply, so a
+// condition created without any of them is rejected by
+// resourceNewRelicAlertConditionCreate instead.
+func validateAlertConditionEntitiesDiff(d *schema.ResourceDiff) error {
+	if d.Get("ignore_remote_entities").(bool) || !d.NewValueKnown("ignore_remote_entities") {
+		return nil
 	}
 
-	termSet := d.Get("term").([]interface{})
-	terms := make([]newrelic.AlertConditionTerm, len(termSet))
+	if d.Get("entities").(*schema.Set).Len() > 0 || !d.NewValueKnown("entities.#") {
+		return nil
+	}
 
-	for i, termI := range termSet {
-		termM := termI.(map[string]interface{})
+	return fmt.Errorf("One of entities, entity_names or entity_label_selector is required unless ignore_remote_entities is set")
+}
 
-		terms[i] = newrelic.AlertConditionTerm{
-			Duration:     termM["duration"].(int),
-			Operator:     termM["operator"].(string),
-			Priority:     termM["priority"].(string),
-			Threshold:    termM["threshold"].(float64),
-			TimeFunction: termM["time_function"].(string),
+// validateAlertConditionDiff checks the planned condition once the fields it
+// reads are known.
+func validateAlertConditionDiff(d *schema.ResourceDiff) error {
+	fields := []string{"type", "metric", "gc_metric", "condition_scope", "user_defined_metric", "user_defined_value_function"}
+	for _, k := range fields {
+		if !d.NewValueKnown(k) {
+			log.Printf("[DEBUG] Not validating New Relic alert condition until %s is known", k)
+			return nil
 		}
 	}
 
+	if err := validateAlertConditionTermsDiff(d, d.Get("metric").(string)); err != nil {
+		return err
+	}
+
+	condition := newrelic.AlertCondition{
+		Type:     d.Get("type").(string),
+		Metric:   d.Get("metric").(string),
+		GCMetric: d.Get("gc_metric").(string),
+		Scope:    d.Get("condition_scope").(string),
+	}
+	condition.UserDefined.Metric = d.Get("user_defined_metric").(string)
+	condition.UserDefined.ValueFunction = d.Get("user_defined_value_function").(string)
+
+	return validateAlertCondition(&condition)
+}
+
+// resourceNewRelicAlertConditionTermHash hashes every field of a term, so
+// that two terms with the same priority both reach validateAlertConditionTerms.
+// Durations and thresholds are normalized first, as "1h" and "60" are equal.
+func resourceNewRelicAlertConditionTermHash(v interface{}) int {
+	m := v.(map[string]interface{})
+
+	priority, _ := m["priority"].(string)
+	if priority == "" {
+		priority = "critical"
+	}
+
+	operator, _ := m["operator"].(string)
+	if operator == "" {
+		operator = "equal"
+	}
+
+	duration, _ := m["duration"].(string)
+	if minutes, err := parseAlertConditionDuration(duration); err == nil {
+		duration = strconv.Itoa(minutes)
+	}
+
+	threshold, _ := m["threshold"].(string)
+	if value, _, err := parseAlertConditionThreshold(threshold); err == nil {
+		threshold = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+
+	timeFunction, _ := m["time_function"].(string)
+
+	return hashcode.String(fmt.Sprintf("%s-%s-%s-%s-%s-", priority, duration, operator, threshold, timeFunction))
+}
+
+func buildAlertConditionStruct(d *schema.ResourceData) (*newrelic.AlertCondition, error) {
+	entitySet := d.Get("entities").(*schema.Set).List()
+	entities := make([]string, len(entitySet))
+
+	for i, entity := range entitySet {
+		entities[i] = strconv.Itoa(entity.(int))
+	}
+
+	terms, err := expandAlertConditionTerms(d)
+	if err != nil {
+		return nil, err
+	}
+
 	condition := newrelic.AlertCondition{
 		Type:                d.Get("type").(string),
 		Name:                d.Get("name").(string),
-		Enabled:             true,
+		Enabled:             d.Get("enabled").(bool),
 		Entities:            entities,
 		Metric:              d.Get("metric").(string),
 		Terms:               terms,
@@ -214,16 +960,150 @@ func buildAlertConditionStruct(d *schema.ResourceData) *newrelic.AlertCondition
 		condition.RunbookURL = attr.(string)
 	}
 
-	if attrM, ok := d.GetOk("user_defined_metric"); ok {
-		if attrVF, ok := d.GetOk("user_defined_value_function"); ok {
-			condition.UserDefined = newrelic.AlertConditionUserDefined{
-				Metric:        attrM.(string),
-				ValueFunction: attrVF.(string),
-			}
+	if attr, ok := d.GetOk("user_defined_metric"); ok {
+		condition.UserDefined.Metric = attr.(string)
+	}
+
+	if attr, ok := d.GetOk("user_defined_value_function"); ok {
+		condition.UserDefined.ValueFunction = attr.(string)
+	}
+
+	return &condition, nil
+}
+
+// mergeEntities returns the remote entities followed by any configured
+// entities not already present, so an update never drops entities that were
+// attached outside of this resource. Entities removed from the configuration
+// since the last apply are dropped, as this resource attached them.
+func mergeEntities(remote []string, configured []string, removed []string) []string {
+	merged := make([]string, 0, len(remote)+len(configured))
+	seen := make(map[string]bool, len(remote)+len(configured)+len(removed))
+
+	for _, entity := range removed {
+		seen[entity] = true
+	}
+
+	for _, entity := range configured {
+		delete(seen, entity)
+	}
+
+	for _, entity := range append(remote, configured...) {
+		if !seen[entity] {
+			seen[entity] = true
+			merged = append(merged, entity)
 		}
 	}
 
-	return &condition
+	return merged
+}
+
+// removedAlertConditionEntities returns the entities that were in the
+// previous configuration and are not in the current one.
+func removedAlertConditionEntities(d *schema.ResourceData) []string {
+	o, n := d.GetChange("entities")
+
+	var removed []string
+	for _, entity := range o.(*schema.Set).Difference(n.(*schema.Set)).List() {
+		removed = append(removed, strconv.Itoa(entity.(int)))
+	}
+
+	return removed
+}
+
+// resolveAlertConditionEntityNamesDiff resolves entity_names, if configured,
+// into the planned entities, so that unknown or ambiguous names fail at plan
+// time and the resolved IDs show in the plan.
+func resolveAlertConditionEntityNamesDiff(d *schema.ResourceDiff, meta interface{}) error {
+	v, ok := d.GetOk("entity_names")
+	if !ok {
+		return nil
+	}
+
+	var names []string
+	for _, name := range v.(*schema.Set).List() {
+		names = append(names, name.(string))
+	}
+	sort.Strings(names)
+
+	// An empty name is one that is not known until apply.
+	if !d.NewValueKnown("entity_names") || !d.NewValueKnown("type") || stringInSlice("", names) {
+		return d.SetNewComputed("entities")
+	}
+
+	conditionType := d.Get("type").(string)
+
+	entities, err := listAlertConditionEntities(meta.(*ProviderConfig).Client, conditionType)
+	if err != nil {
+		return err
+	}
+
+	ids, err := resolveAlertConditionEntityNames(conditionType, entities, names)
+	if err != nil {
+		return err
+	}
+
+	return d.SetNew("entities", ids)
+}
+
+// resolveAlertConditionLabelSelectorDiff resolves entity_label_selector, if
+// configured, into the planned entities. As this runs with every plan, an
+// entity that is labelled or unlabelled shows up as a change to entities.
+func resolveAlertConditionLabelSelectorDiff(d *schema.ResourceDiff, meta interface{}) error {
+	selector := d.Get("entity_label_selector").(map[string]interface{})
+	if len(selector) == 0 {
+		return nil
+	}
+
+	if d.Get("ignore_remote_entities").(bool) {
+		return fmt.Errorf("ignore_remote_entities cannot be used with entity_label_selector")
+	}
+
+	// An empty label name is one that is not known until apply.
+	known := d.NewValueKnown("entity_label_selector") && d.NewValueKnown("type")
+	for _, name := range selector {
+		known = known && name.(string) != ""
+	}
+
+	if !known {
+		return d.SetNewComputed("entities")
+	}
+
+	labels, err := meta.(*ProviderConfig).Client.ListLabels()
+	if err != nil {
+		return err
+	}
+
+	ids, err := resolveAlertConditionLabelSelector(d.Get("type").(string), labels, selector)
+	if err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return fmt.Errorf("entity_label_selector %v does not match any entities", selector)
+	}
+
+	return d.SetNew("entities", ids)
+}
+
+// readAlertConditionEntityNames reports the remote entities as names when
+// entity_names is used.
+func readAlertConditionEntityNames(client *newrelic.Client, condition *newrelic.AlertCondition, d *schema.ResourceData) error {
+	if _, ok := d.GetOk("entity_names"); !ok || d.Get("ignore_remote_entities").(bool) {
+		return nil
+	}
+
+	entities, err := listAlertConditionEntities(client, condition.Type)
+	if err != nil {
+		return err
+	}
+
+	names := alertConditionEntityNames(entities, condition.Entities)
+
+	if err := d.Set("entity_names", names); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting alert condition entity names: %#v", err)
+	}
+
+	return nil
 }
 
 func readAlertConditionStruct(condition *newrelic.AlertCondition, d *schema.ResourceData) error {
@@ -246,6 +1126,7 @@ func readAlertConditionStruct(condition *newrelic.AlertCondition, d *schema.Reso
 	d.Set("policy_id", policyID)
 	d.Set("name", condition.Name)
 	d.Set("type", condition.Type)
+	d.Set("enabled", condition.Enabled)
 	d.Set("metric", condition.Metric)
 	d.Set("runbook_url", condition.RunbookURL)
 	d.Set("condition_scope", condition.Scope)
@@ -253,48 +1134,47 @@ func readAlertConditionStruct(condition *newrelic.AlertCondition, d *schema.Reso
 	d.Set("gc_metric", condition.GCMetric)
 	d.Set("user_defined_metric", condition.UserDefined.Metric)
 	d.Set("user_defined_value_function", condition.UserDefined.ValueFunction)
-	if err := d.Set("entities", entities); err != nil {
-		return fmt.Errorf("[DEBUG] Error setting alert condition entities: %#v", err)
-	}
-
-	var terms []map[string]interface{}
 
-	for _, src := range condition.Terms {
-		dst := map[string]interface{}{
-			"duration":      src.Duration,
-			"operator":      src.Operator,
-			"priority":      src.Priority,
-			"threshold":     src.Threshold,
-			"time_function": src.TimeFunction,
+	// Entity membership may be managed by newrelic_alert_entity_condition
+	// resources instead, in which case the remote list is not authoritative.
+	if !d.Get("ignore_remote_entities").(bool) {
+		if err := d.Set("entities", entities); err != nil {
+			return fmt.Errorf("[DEBUG] Error setting alert condition entities: %#v", err)
 		}
-		terms = append(terms, dst)
 	}
 
-	if err := d.Set("term", terms); err != nil {
-		return fmt.Errorf("[DEBUG] Error setting alert condition terms: %#v", err)
+	if err := flattenAlertConditionTerms(d, condition.Terms); err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func resourceNewRelicAlertConditionCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	condition := buildAlertConditionStruct(d)
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildAlertConditionStruct(d)
+	if err != nil {
+		return err
+	}
+
+	if len(condition.Entities) == 0 && !d.Get("ignore_remote_entities").(bool) {
+		return fmt.Errorf("One of entities, entity_names or entity_label_selector is required unless ignore_remote_entities is set")
+	}
 
 	log.Printf("[INFO] Creating New Relic alert condition %s", condition.Name)
 
-	condition, err := client.CreateAlertCondition(*condition)
+	condition, err = client.CreateAlertCondition(*condition)
 	if err != nil {
 		return err
 	}
 
 	d.SetId(serializeIDs([]int{condition.PolicyID, condition.ID}))
 
-	return nil
+	return readAlertConditionStruct(condition, d)
 }
 
 func resourceNewRelicAlertConditionRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	log.Printf("[INFO] Reading New Relic alert condition %s", d.Id())
 
@@ -316,12 +1196,19 @@ func resourceNewRelicAlertConditionRead(d *schema.ResourceData, meta interface{}
 		return err
 	}
 
-	return readAlertConditionStruct(condition, d)
+	if err := readAlertConditionStruct(condition, d); err != nil {
+		return err
+	}
+
+	return readAlertConditionEntityNames(client, condition, d)
 }
 
 func resourceNewRelicAlertConditionUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	condition := buildAlertConditionStruct(d)
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildAlertConditionStruct(d)
+	if err != nil {
+		return err
+	}
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
@@ -334,6 +1221,15 @@ func resourceNewRelicAlertConditionUpdate(d *schema.ResourceData, meta interface
 	condition.PolicyID = policyID
 	condition.ID = id
 
+	if d.Get("ignore_remote_entities").(bool) {
+		current, err := client.GetAlertCondition(policyID, id)
+		if err != nil {
+			return err
+		}
+
+		condition.Entities = mergeEntities(current.Entities, condition.Entities, removedAlertConditionEntities(d))
+	}
+
 	log.Printf("[INFO] Updating New Relic alert condition %d", id)
 
 	updatedCondition, err := client.UpdateAlertCondition(*condition)
@@ -345,7 +1241,7 @@ func resourceNewRelicAlertConditionUpdate(d *schema.ResourceData, meta interface
 }
 
 func resourceNewRelicAlertConditionDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
@@ -0,0 +1,76 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func resourceNewRelicAlertConditionMigrateState(v int, is *terraform.InstanceState, meta interface{}) (*terraform.InstanceState, error) {
+	switch v {
+	case 0:
+		log.Println("[INFO] Found New Relic alert condition state v0; migrating to v1")
+		return migrateNewRelicAlertConditionStateV0toV1(is)
+	default:
+		return is, fmt.Errorf("Unexpected schema version: %d", v)
+	}
+}
+
+// migrateNewRelicAlertConditionStateV0toV1 rewrites the list indexes of
+// entities, entity_names and term to the set hashes used from v1 on.
+func migrateNewRelicAlertConditionStateV0toV1(is *terraform.InstanceState) (*terraform.InstanceState, error) {
+	if is.Empty() || is.Attributes == nil {
+		log.Println("[DEBUG] Empty InstanceState; nothing to migrate.")
+		return is, nil
+	}
+
+	log.Printf("[DEBUG] Attributes before migration: %#v", is.Attributes)
+
+	hashInt := schema.HashSchema(&schema.Schema{Type: schema.TypeInt})
+	attributes := make(map[string]string, len(is.Attributes))
+
+	for k, v := range is.Attributes {
+		parts := strings.SplitN(k, ".", 3)
+		if len(parts) < 2 || parts[1] == "#" {
+			attributes[k] = v
+			continue
+		}
+
+		switch parts[0] {
+		case "entities":
+			id, err := strconv.Atoi(v)
+			if err != nil {
+				return is, fmt.Errorf("Error parsing alert condition entity %q: %s", v, err)
+			}
+
+			attributes[fmt.Sprintf("entities.%d", hashInt(id))] = v
+		case "entity_names":
+			attributes[fmt.Sprintf("entity_names.%d", schema.HashString(v))] = v
+		case "term":
+			if len(parts) != 3 {
+				attributes[k] = v
+				continue
+			}
+
+			term := make(map[string]interface{})
+			for _, field := range []string{"duration", "operator", "priority", "threshold", "time_function"} {
+				term[field] = is.Attributes[fmt.Sprintf("term.%s.%s", parts[1], field)]
+			}
+
+			hash := resourceNewRelicAlertConditionTermHash(term)
+			attributes[fmt.Sprintf("term.%d.%s", hash, parts[2])] = v
+		default:
+			attributes[k] = v
+		}
+	}
+
+	is.Attributes = attributes
+
+	log.Printf("[DEBUG] Attributes after migration: %#v", is.Attributes)
+
+	return is, nil
+}
@@ -0,0 +1,103 @@
+package newrelic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestNewRelicAlertConditionMigrateState(t *testing.T) {
+	hashInt := schema.HashSchema(&schema.Schema{Type: schema.TypeInt})
+	warning := map[string]interface{}{"priority": "warning", "threshold": "0.85"}
+	critical := map[string]interface{}{"priority": "critical", "threshold": "0.75"}
+
+	cases := map[string]struct {
+		StateVersion int
+		Attributes   map[string]string
+		Expected     map[string]string
+	}{
+		"v0_entities_and_terms": {
+			StateVersion: 0,
+			Attributes: map[string]string{
+				"name":                       "foo",
+				"entities.#":                 "2",
+				"entities.0":                 "456",
+				"entities.1":                 "123",
+				"term.#":                     "2",
+				"term.0.priority":            "warning",
+				"term.0.threshold":           "0.85",
+				"term.1.priority":            "critical",
+				"term.1.threshold":           "0.75",
+				"entity_label_selector.%":    "1",
+				"entity_label_selector.Team": "payments",
+			},
+			Expected: map[string]string{
+				"name":                                   "foo",
+				"entities.#":                             "2",
+				fmt.Sprintf("entities.%d", hashInt(456)): "456",
+				fmt.Sprintf("entities.%d", hashInt(123)): "123",
+				"term.#":                                 "2",
+				testAccNewRelicAlertConditionTermKey(warning, "priority"):   "warning",
+				testAccNewRelicAlertConditionTermKey(warning, "threshold"):  "0.85",
+				testAccNewRelicAlertConditionTermKey(critical, "priority"):  "critical",
+				testAccNewRelicAlertConditionTermKey(critical, "threshold"): "0.75",
+				"entity_label_selector.%":                                   "1",
+				"entity_label_selector.Team":                                "payments",
+			},
+		},
+		"v0_entity_names": {
+			StateVersion: 0,
+			Attributes: map[string]string{
+				"entity_names.#": "1",
+				"entity_names.0": "my-app",
+			},
+			Expected: map[string]string{
+				"entity_names.#": "1",
+				fmt.Sprintf("entity_names.%d", schema.HashString("my-app")): "my-app",
+			},
+		},
+	}
+
+	for tn, tc := range cases {
+		is := &terraform.InstanceState{
+			ID:         "1:2",
+			Attributes: tc.Attributes,
+		}
+
+		is, err := resourceNewRelicAlertConditionMigrateState(tc.StateVersion, is, nil)
+		if err != nil {
+			t.Fatalf("bad: %s, err: %#v", tn, err)
+		}
+
+		if len(is.Attributes) != len(tc.Expected) {
+			t.Fatalf("bad: %s\n\n expected: %#v\n got: %#v", tn, tc.Expected, is.Attributes)
+		}
+
+		for k, v := range tc.Expected {
+			if is.Attributes[k] != v {
+				t.Fatalf("bad: %s\n\n expected: %s = %#v\n got: %#v", tn, k, v, is.Attributes)
+			}
+		}
+	}
+}
+
+func TestNewRelicAlertConditionMigrateState_empty(t *testing.T) {
+	var is *terraform.InstanceState
+
+	// should handle nil
+	is, err := resourceNewRelicAlertConditionMigrateState(0, is, nil)
+	if err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+	if is != nil {
+		t.Fatalf("expected nil instancestate, got: %#v", is)
+	}
+
+	// should handle non-nil but empty
+	is = &terraform.InstanceState{}
+	if _, err := resourceNewRelicAlertConditionMigrateState(0, is, nil); err != nil {
+		t.Fatalf("err: %#v", err)
+	}
+}
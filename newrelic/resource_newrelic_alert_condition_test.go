@@ -2,10 +2,15 @@ package newrelic
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 	newrelic "github.com/paultyng/go-newrelic/api"
 )
@@ -25,22 +30,19 @@ func TestAccNewRelicAlertCondition_Basic(t *testing.T) {
 						"newrelic_alert_condition.foo", "name", fmt.Sprintf("tf-test-%s", rName)),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "type", "apm_app_metric"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_condition.foo", "enabled", "true"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "runbook_url", "https://foo.example.com"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "entities.#", "1"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "term.#", "1"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.duration", "5"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.operator", "below"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.priority", "critical"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.threshold", "0.75"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.time_function", "all"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "duration", "5"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "operator", "below"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "priority", "critical"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "threshold", "0.75"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "time_function", "all"),
 				),
 			},
 			{
@@ -49,22 +51,19 @@ func TestAccNewRelicAlertCondition_Basic(t *testing.T) {
 					testAccCheckNewRelicAlertConditionExists("newrelic_alert_condition.foo"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "name", fmt.Sprintf("tf-test-updated-%s", rName)),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_condition.foo", "enabled", "false"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "runbook_url", "https://bar.example.com"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "entities.#", "1"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "term.#", "1"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.duration", "10"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.operator", "below"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.priority", "critical"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.threshold", "0.65"),
-					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.time_function", "all"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "duration", "10"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "operator", "below"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "priority", "critical"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "threshold", "0.65"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "time_function", "all"),
 				),
 			},
 		},
@@ -92,26 +91,890 @@ func TestAccNewRelicAlertCondition_ZeroThreshold(t *testing.T) {
 						"newrelic_alert_condition.foo", "entities.#", "1"),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_condition.foo", "term.#", "1"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "duration", "5"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "operator", "below"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "priority", "critical"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "threshold", "0"),
+					testAccCheckNewRelicAlertConditionTermAttr("newrelic_alert_condition.foo", "critical", "time_function", "all"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicAlertCondition_EntityNames(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckNewRelicAlertConditionConfigEntityNames(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertConditionExists("newrelic_alert_condition.foo"),
 					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.duration", "5"),
+						"newrelic_alert_condition.foo", "entity_names.#", "1"),
 					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.operator", "below"),
+						"newrelic_alert_condition.foo", fmt.Sprintf("entity_names.%d", schema.HashString(testAccExpectedApplicationName)), testAccExpectedApplicationName),
+					testAccCheckNewRelicAlertConditionEntity("newrelic_alert_condition.foo", "data.newrelic_application.app"),
+				),
+			},
+			{
+				Config:      testAccCheckNewRelicAlertConditionConfigEntityNamesUnknown(rName),
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("does not match any entity for apm_app_metric conditions"),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicAlertCondition_EntityLabelSelector(t *testing.T) {
+	rName := acctest.RandString(5)
+	labelKey := fmt.Sprintf("tf-test-%s:payments", rName)
+	defer testAccNewRelicAlertConditionDeleteLabel(t, labelKey)
+
+	// otherID is the application the label is also linked to in the second step.
+	var otherID int
+
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					testAccNewRelicAlertConditionCreateLabel(t, fmt.Sprintf("tf-test-%s", rName), "payments")
+				},
+				Config: testAccCheckNewRelicAlertConditionConfigEntityLabelSelector(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertConditionExists("newrelic_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_condition.foo", "entity_label_selector.%", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_condition.foo", "entities.#", "1"),
+					testAccCheckNewRelicAlertConditionEntity("newrelic_alert_condition.foo", "data.newrelic_application.app"),
+				),
+			},
+			{
+				SkipFunc: func() (bool, error) {
+					otherID = testAccNewRelicAlertConditionOtherApplication(t)
+					return otherID == 0, nil
+				},
+				PreConfig: func() {
+					testAccNewRelicAlertConditionLinkLabel(t, fmt.Sprintf("tf-test-%s", rName), "payments", otherID)
+				},
+				Config:             testAccCheckNewRelicAlertConditionConfigEntityLabelSelector(rName),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+			{
+				SkipFunc: func() (bool, error) {
+					return otherID == 0, nil
+				},
+				Config: testAccCheckNewRelicAlertConditionConfigEntityLabelSelector(rName),
+				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.priority", "critical"),
+						"newrelic_alert_condition.foo", "entities.#", "2"),
+				),
+			},
+		},
+	})
+}
+
+// testAccNewRelicAlertConditionOtherApplication returns the ID of an
+// application other than the test application, or 0 if there is none.
+func testAccNewRelicAlertConditionOtherApplication(t *testing.T) int {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+
+	applications, err := client.ListApplications()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, application := range applications {
+		if application.Name != testAccExpectedApplicationName {
+			return application.ID
+		}
+	}
+
+	return 0
+}
+
+func testAccNewRelicAlertConditionLinkLabel(t *testing.T, category string, name string, applicationID int) {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+
+	err := client.CreateLabel(newrelic.Label{
+		Category: category,
+		Name:     name,
+		Links: newrelic.LabelLinks{
+			Applications: []int{applicationID},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testAccNewRelicAlertConditionCreateLabel(t *testing.T, category string, name string) {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+
+	applications, err := client.ListApplications()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, application := range applications {
+		if application.Name != testAccExpectedApplicationName {
+			continue
+		}
+
+		testAccNewRelicAlertConditionLinkLabel(t, category, name, application.ID)
+		return
+	}
+
+	t.Fatalf("application %q not found", testAccExpectedApplicationName)
+}
+
+func testAccNewRelicAlertConditionDeleteLabel(t *testing.T, key string) {
+	if testAccProvider.Meta() == nil {
+		return
+	}
+
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+	if err := client.DeleteLabel(key); err != nil && err != newrelic.ErrNotFound {
+		t.Error(err)
+	}
+}
+
+func TestAccNewRelicAlertCondition_Units(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckNewRelicAlertConditionConfigUnits(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertConditionExists("newrelic_alert_condition.foo"),
 					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.threshold", "0"),
+						"newrelic_alert_condition.foo", "critical.0.duration", "10"),
 					resource.TestCheckResourceAttr(
-						"newrelic_alert_condition.foo", "term.0.time_function", "all"),
+						"newrelic_alert_condition.foo", "critical.0.threshold", "0.5"),
 				),
 			},
 		},
 	})
 }
 
+func TestResolveAlertConditionLabelSelector(t *testing.T) {
+	labels := []newrelic.Label{
+		{Key: "Team:payments", Links: newrelic.LabelLinks{Applications: []int{3, 1, 2}, Servers: []int{10}}},
+		{Key: "Env:prod", Links: newrelic.LabelLinks{Applications: []int{2, 3, 4}}},
+	}
+
+	ids, err := resolveAlertConditionLabelSelector("apm_app_metric", labels, map[string]interface{}{
+		"Team": "payments",
+		"Env":  "prod",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ids, []int{2, 3}) {
+		t.Fatalf("expected [2 3], got %v", ids)
+	}
+
+	ids, err = resolveAlertConditionLabelSelector("servers_metric", labels, map[string]interface{}{"Team": "payments"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ids, []int{10}) {
+		t.Fatalf("expected [10], got %v", ids)
+	}
+
+	ids, err = resolveAlertConditionLabelSelector("apm_app_metric", labels, map[string]interface{}{
+		"Team": "payments",
+		"Env":  "staging",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(ids) != 0 {
+		t.Fatalf("expected no entities, got %v", ids)
+	}
+
+	if _, err := resolveAlertConditionLabelSelector("browser_metric", labels, map[string]interface{}{"Team": "payments"}); err == nil {
+		t.Fatal("expected an error for an unsupported condition type")
+	}
+}
+
+func TestResolveAlertConditionEntityNames(t *testing.T) {
+	entities := []alertConditionEntity{
+		{ID: 1, Name: "foo"},
+		{ID: 2, Name: "bar"},
+		{ID: 3, Name: "bar"},
+	}
+
+	ids, err := resolveAlertConditionEntityNames("apm_app_metric", entities, []string{"foo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(ids, []int{1}) {
+		t.Fatalf("expected [1], got %v", ids)
+	}
+
+	if _, err := resolveAlertConditionEntityNames("apm_app_metric", entities, []string{"baz"}); err == nil {
+		t.Fatal("expected an error for an unknown name")
+	}
+
+	_, err = resolveAlertConditionEntityNames("apm_app_metric", entities, []string{"bar"})
+	if err == nil || !regexp.MustCompile("matches 2 entities").MatchString(err.Error()) {
+		t.Fatalf("expected an error for a duplicate name, got %v", err)
+	}
+
+	names := alertConditionEntityNames(entities, []string{"1", "4"})
+	if !reflect.DeepEqual(names, []string{"foo", "4"}) {
+		t.Fatalf("expected [foo 4], got %v", names)
+	}
+}
+
+func TestMergeEntities(t *testing.T) {
+	cases := []struct {
+		remote     []string
+		configured []string
+		removed    []string
+		expected   []string
+	}{
+		// entities attached elsewhere are kept
+		{
+			remote:     []string{"1", "2"},
+			configured: []string{"3"},
+			expected:   []string{"1", "2", "3"},
+		},
+		// entities removed from the configuration are dropped
+		{
+			remote:     []string{"1", "2", "3"},
+			configured: []string{"3"},
+			removed:    []string{"2"},
+			expected:   []string{"1", "3"},
+		},
+		// an entity removed and added back is kept
+		{
+			remote:     []string{"1", "2"},
+			configured: []string{"2"},
+			removed:    []string{"2"},
+			expected:   []string{"1", "2"},
+		},
+	}
+
+	for i, c := range cases {
+		if actual := mergeEntities(c.remote, c.configured, c.removed); !reflect.DeepEqual(actual, c.expected) {
+			t.Fatalf("case %d: expected %v, got %v", i, c.expected, actual)
+		}
+	}
+}
+
+func TestValidateAlertCondition(t *testing.T) {
+	cases := []struct {
+		condition   newrelic.AlertCondition
+		expectedErr *regexp.Regexp
+	}{
+		{
+			condition: newrelic.AlertCondition{Type: "apm_app_metric", Metric: "apdex", Scope: "application"},
+		},
+		{
+			condition: newrelic.AlertCondition{Type: "apm_jvm_metric", Metric: "gc_cpu_time", GCMetric: "GC/G1 Young Generation", Scope: "instance"},
+		},
+		{
+			condition: newrelic.AlertCondition{
+				Type:        "servers_metric",
+				Metric:      "user_defined",
+				UserDefined: newrelic.AlertConditionUserDefined{Metric: "Custom/foo", ValueFunction: "average"},
+			},
+		},
+		{
+			condition:   newrelic.AlertCondition{Type: "apm_kt_metric", Metric: "response_time_web"},
+			expectedErr: regexp.MustCompile("expected one of: apdex, error_count, error_percentage, response_time, throughput"),
+		},
+		{
+			condition:   newrelic.AlertCondition{Type: "apm_app_metric", Metric: "apdex", GCMetric: "GC/G1 Young Generation"},
+			expectedErr: regexp.MustCompile("gc_metric is only valid for apm_jvm_metric"),
+		},
+		{
+			condition:   newrelic.AlertCondition{Type: "apm_jvm_metric", Metric: "gc_cpu_time"},
+			expectedErr: regexp.MustCompile("gc_metric is required"),
+		},
+		{
+			condition: newrelic.AlertCondition{
+				Type:        "apm_app_metric",
+				Metric:      "apdex",
+				UserDefined: newrelic.AlertConditionUserDefined{Metric: "Custom/foo"},
+			},
+			expectedErr: regexp.MustCompile("require metric = \"user_defined\""),
+		},
+		{
+			condition: newrelic.AlertCondition{
+				Type:        "mobile_metric",
+				Metric:      "user_defined",
+				UserDefined: newrelic.AlertConditionUserDefined{Metric: "Custom/foo"},
+			},
+			expectedErr: regexp.MustCompile("requires both user_defined_metric and user_defined_value_function"),
+		},
+		{
+			condition:   newrelic.AlertCondition{Type: "browser_metric", Metric: "end_user_apdex", Scope: "instance"},
+			expectedErr: regexp.MustCompile("expected one of: apm_app_metric, apm_jvm_metric"),
+		},
+	}
+
+	for i, c := range cases {
+		err := validateAlertCondition(&c.condition)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestResourceNewRelicAlertConditionCustomizeDiff(t *testing.T) {
+	cases := []struct {
+		raw         map[string]interface{}
+		expectedErr *regexp.Regexp
+	}{
+		{
+			raw: map[string]interface{}{"type": "apm_app_metric", "metric": "apdex"},
+		},
+		{
+			raw: map[string]interface{}{"type": "apm_jvm_metric", "metric": "gc_cpu_time", "gc_metric": "GC/G1 Young Generation", "condition_scope": "instance"},
+		},
+		{
+			raw:         map[string]interface{}{"type": "apm_kt_metric", "metric": "response_time_web"},
+			expectedErr: regexp.MustCompile("metric \"response_time_web\" is not valid for apm_kt_metric conditions"),
+		},
+		{
+			raw:         map[string]interface{}{"type": "apm_jvm_metric", "metric": "gc_cpu_time"},
+			expectedErr: regexp.MustCompile("gc_metric is required"),
+		},
+		// checked once the metric is known
+		{
+			raw: map[string]interface{}{"type": "apm_jvm_metric", "metric": "${var.unknown}"},
+		},
+		{
+			raw:         map[string]interface{}{"type": "servers_metric", "metric": "user_defined", "user_defined_metric": "Custom/foo"},
+			expectedErr: regexp.MustCompile("requires both user_defined_metric and user_defined_value_function"),
+		},
+		{
+			raw:         map[string]interface{}{"type": "browser_metric", "metric": "end_user_apdex", "condition_scope": "instance"},
+			expectedErr: regexp.MustCompile("condition_scope \"instance\" is not valid for browser_metric conditions"),
+		},
+	}
+
+	for i, c := range cases {
+		raw := map[string]interface{}{
+			"policy_id": 1,
+			"name":      "foo",
+			"entities":  []interface{}{1},
+			"term": []interface{}{
+				map[string]interface{}{"duration": "5", "operator": "below", "threshold": "0.75", "time_function": "all"},
+			},
+		}
+		for k, v := range c.raw {
+			raw[k] = v
+		}
+
+		_, err := testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw, nil)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestResourceNewRelicAlertConditionCustomizeDiff_terms(t *testing.T) {
+	term := func(priority string, operator string, duration string, threshold string) map[string]interface{} {
+		return map[string]interface{}{
+			"duration":      duration,
+			"operator":      operator,
+			"priority":      priority,
+			"threshold":     threshold,
+			"time_function": "all",
+		}
+	}
+
+	cases := []struct {
+		raw         map[string]interface{}
+		expectedErr *regexp.Regexp
+	}{
+		{
+			raw: map[string]interface{}{
+				"term": []interface{}{term("critical", "above", "5", "2"), term("warning", "above", "5", "1")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "1h", "2")},
+				"warning":  []interface{}{term("", "above", "60", "1")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"term": []interface{}{term("critical", "above", "5", "2"), term("critical", "above", "10", "3")},
+			},
+			expectedErr: regexp.MustCompile("only one critical term is allowed"),
+		},
+		{
+			raw: map[string]interface{}{
+				"term": []interface{}{term("warning", "above", "5", "1")},
+			},
+			expectedErr: regexp.MustCompile("a critical term is required"),
+		},
+		{
+			raw: map[string]interface{}{
+				"term": []interface{}{term("critical", "above", "5", "2"), term("warning", "below", "5", "1")},
+			},
+			expectedErr: regexp.MustCompile("must match critical operator"),
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "5", "2")},
+				"warning":  []interface{}{term("", "above", "10", "1")},
+			},
+			expectedErr: regexp.MustCompile("warning duration 10 must match critical duration 5"),
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "5", "2")},
+				"warning":  []interface{}{term("", "above", "5", "3")},
+			},
+			expectedErr: regexp.MustCompile("must be below critical threshold 2"),
+		},
+		// checked once the thresholds are known
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "5", "2")},
+				"warning":  []interface{}{term("", "above", "5", "${var.unknown}")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "5", "2.5%")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("", "above", "5", "500ms")},
+			},
+			expectedErr: regexp.MustCompile("measures time, but metric error_percentage measures percent"),
+		},
+		{
+			raw: map[string]interface{}{
+				"metric":   "apdex",
+				"critical": []interface{}{term("", "below", "5", "0.8rps")},
+			},
+			expectedErr: regexp.MustCompile("metric apdex has no unit"),
+		},
+	}
+
+	for i, c := range cases {
+		raw := map[string]interface{}{
+			"policy_id": 1,
+			"name":      "foo",
+			"type":      "apm_app_metric",
+			"entities":  []interface{}{1},
+			"metric":    "error_percentage",
+		}
+		for k, v := range c.raw {
+			raw[k] = v
+		}
+
+		_, err := testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw, nil)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestResourceNewRelicAlertConditionCustomizeDiff_entityNames(t *testing.T) {
+	meta, closeServer := testProviderConfig(t, map[string]string{
+		"/applications.json": `{"applications": [{"id": 1, "name": "foo"}, {"id": 2, "name": "bar"}, {"id": 3, "name": "bar"}]}`,
+	})
+	defer closeServer()
+
+	raw := func(names ...interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"policy_id":    1,
+			"name":         "foo",
+			"type":         "apm_app_metric",
+			"metric":       "apdex",
+			"entity_names": names,
+			"term": []interface{}{
+				map[string]interface{}{"duration": "5", "operator": "below", "threshold": "0.75", "time_function": "all"},
+			},
+		}
+	}
+
+	diff, err := testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw("foo"), meta)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	hash := schema.HashSchema(&schema.Schema{Type: schema.TypeInt})(1)
+	if attr := diff.Attributes[fmt.Sprintf("entities.%d", hash)]; attr == nil || attr.New != "1" {
+		t.Fatalf("expected entity 1 in the plan, got %#v", diff.Attributes)
+	}
+
+	_, err = testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw("baz"), meta)
+	if err == nil || !regexp.MustCompile("does not match any entity").MatchString(err.Error()) {
+		t.Fatalf("expected an error for an unknown name, got %v", err)
+	}
+
+	_, err = testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw("bar"), meta)
+	if err == nil || !regexp.MustCompile("matches 2 entities").MatchString(err.Error()) {
+		t.Fatalf("expected an error for a duplicate name, got %v", err)
+	}
+
+	// resolved at apply time once the name is known
+	diff, err = testResourceDiff(t, resourceNewRelicAlertCondition(), nil, raw("${var.unknown}"), meta)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if attr := diff.Attributes["entities.#"]; attr == nil || !attr.NewComputed {
+		t.Fatalf("expected computed entities, got %#v", diff.Attributes)
+	}
+}
+
+func TestResourceNewRelicAlertConditionCustomizeDiff_entityLabelSelector(t *testing.T) {
+	raw := map[string]interface{}{
+		"policy_id":             1,
+		"name":                  "foo",
+		"type":                  "apm_app_metric",
+		"metric":                "apdex",
+		"entity_label_selector": map[string]interface{}{"Team": "payments"},
+		"term": []interface{}{
+			map[string]interface{}{"duration": "5", "operator": "below", "threshold": "0.75", "time_function": "all"},
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNewRelicAlertCondition().Schema, raw)
+	d.SetId("1:2")
+	if err := d.Set("entities", []int{1}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	state := d.State()
+
+	cases := []struct {
+		labels   string
+		entities bool
+	}{
+		{
+			labels: `{"labels": [{"key": "Team:payments", "links": {"applications": [1], "servers": []}}]}`,
+		},
+		// application 2 was labelled since the last apply
+		{
+			labels:   `{"labels": [{"key": "Team:payments", "links": {"applications": [1, 2], "servers": []}}]}`,
+			entities: true,
+		},
+	}
+
+	for i, c := range cases {
+		meta, closeServer := testProviderConfig(t, map[string]string{"/labels.json": c.labels})
+
+		diff, err := testResourceDiff(t, resourceNewRelicAlertCondition(), state, raw, meta)
+		closeServer()
+		if err != nil {
+			t.Fatalf("case %d: unexpected error: %s", i, err)
+		}
+
+		if changed := diff != nil && diff.Attributes["entities.#"] != nil; changed != c.entities {
+			t.Fatalf("case %d: expected a change to entities: %t, got %#v", i, c.entities, diff)
+		}
+	}
+
+	meta, closeServer := testProviderConfig(t, map[string]string{"/labels.json": `{"labels": []}`})
+	defer closeServer()
+
+	_, err := testResourceDiff(t, resourceNewRelicAlertCondition(), state, raw, meta)
+	if err == nil || !regexp.MustCompile("does not match any entities").MatchString(err.Error()) {
+		t.Fatalf("expected an error without matching entities, got %v", err)
+	}
+}
+
+func TestResourceNewRelicAlertConditionCustomizeDiff_entities(t *testing.T) {
+	d := schema.TestResourceDataRaw(t, resourceNewRelicAlertCondition().Schema, map[string]interface{}{})
+	d.SetId("1:2")
+	if err := d.Set("entities", []int{1}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	cases := []struct {
+		state       *terraform.InstanceState
+		raw         map[string]interface{}
+		expectedErr *regexp.Regexp
+	}{
+		{
+			raw: map[string]interface{}{"entities": []interface{}{1}},
+		},
+		{
+			raw:         map[string]interface{}{"entities": []interface{}{}},
+			expectedErr: regexp.MustCompile("One of entities, entity_names or entity_label_selector is required"),
+		},
+		{
+			state:       d.State(),
+			raw:         map[string]interface{}{"entities": []interface{}{}},
+			expectedErr: regexp.MustCompile("One of entities, entity_names or entity_label_selector is required"),
+		},
+		// entities are attached with newrelic_alert_entity_condition
+		{
+			raw: map[string]interface{}{"entities": []interface{}{}, "ignore_remote_entities": true},
+		},
+		// checked once the entities are known
+		{
+			raw: map[string]interface{}{"entities": "${var.unknown}"},
+		},
+		{
+			raw: map[string]interface{}{"entity_names": []interface{}{"${var.unknown}"}},
+		},
+	}
+
+	for i, c := range cases {
+		raw := map[string]interface{}{
+			"policy_id": 1,
+			"name":      "foo",
+			"type":      "apm_app_metric",
+			"metric":    "apdex",
+			"term": []interface{}{
+				map[string]interface{}{"duration": "5", "operator": "below", "threshold": "0.75", "time_function": "all"},
+			},
+		}
+		for k, v := range c.raw {
+			raw[k] = v
+		}
+
+		_, err := testResourceDiff(t, resourceNewRelicAlertCondition(), c.state, raw, nil)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestValidateAlertConditionTerms(t *testing.T) {
+	critical := newrelic.AlertConditionTerm{Duration: 5, Operator: "above", Priority: "critical", Threshold: 10, TimeFunction: "all"}
+	warning := newrelic.AlertConditionTerm{Duration: 5, Operator: "above", Priority: "warning", Threshold: 5, TimeFunction: "all"}
+
+	warningAboveCritical := warning
+	warningAboveCritical.Threshold = 15
+
+	criticalBelow, warningBelow := critical, warning
+	criticalBelow.Operator, warningBelow.Operator = "below", "below"
+
+	warningLonger := warning
+	warningLonger.Duration = 10
+
+	cases := []struct {
+		terms       []newrelic.AlertConditionTerm
+		expectedErr *regexp.Regexp
+	}{
+		{
+			terms: []newrelic.AlertConditionTerm{critical},
+		},
+		{
+			terms: []newrelic.AlertConditionTerm{warning, critical},
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{warning},
+			expectedErr: regexp.MustCompile("a critical term is required"),
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{critical, critical},
+			expectedErr: regexp.MustCompile("only one critical term is allowed"),
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{critical, warningAboveCritical},
+			expectedErr: regexp.MustCompile("must be below critical threshold 10"),
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{criticalBelow, warningBelow},
+			expectedErr: regexp.MustCompile("must be above critical threshold 10"),
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{critical, warningBelow},
+			expectedErr: regexp.MustCompile("must match critical operator"),
+		},
+		{
+			terms:       []newrelic.AlertConditionTerm{critical, warningLonger},
+			expectedErr: regexp.MustCompile("warning duration 10 must match critical duration 5"),
+		},
+	}
+
+	for i, c := range cases {
+		err := validateAlertConditionTerms(c.terms)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestResourceNewRelicAlertConditionTermHash(t *testing.T) {
+	term := func(duration string, threshold string) map[string]interface{} {
+		return map[string]interface{}{
+			"duration":      duration,
+			"operator":      "above",
+			"priority":      "critical",
+			"threshold":     threshold,
+			"time_function": "all",
+		}
+	}
+
+	if resourceNewRelicAlertConditionTermHash(term("60", "0.5")) != resourceNewRelicAlertConditionTermHash(term("1h", "500ms")) {
+		t.Fatal("expected equal durations and thresholds to hash alike")
+	}
+
+	if resourceNewRelicAlertConditionTermHash(term("5", "0.5")) == resourceNewRelicAlertConditionTermHash(term("10", "0.5")) {
+		t.Fatal("expected terms with the same priority to hash differently")
+	}
+}
+
+func TestExpandAlertConditionTerm(t *testing.T) {
+	termM := map[string]interface{}{
+		"duration":      "10m",
+		"operator":      "above",
+		"threshold":     "500ms",
+		"time_function": "all",
+	}
+
+	term, err := expandAlertConditionTerm(termM, "critical")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if term.Duration != 10 || term.Threshold != 0.5 {
+		t.Fatalf("expected 10 minutes and 0.5 seconds, got %#v", term)
+	}
+}
+
+func TestValidateAlertConditionThresholdUnit(t *testing.T) {
+	if err := validateAlertConditionThresholdUnit("500ms", "response_time_web"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := validateAlertConditionThresholdUnit("2.5%", "error_percentage"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := validateAlertConditionThresholdUnit("500ms", "error_percentage")
+	if err == nil || !regexp.MustCompile("measures time, but metric error_percentage measures percent").MatchString(err.Error()) {
+		t.Fatalf("expected a unit mismatch error, got %v", err)
+	}
+
+	err = validateAlertConditionThresholdUnit("0.8%", "apdex")
+	if err == nil || !regexp.MustCompile("metric apdex has no unit").MatchString(err.Error()) {
+		t.Fatalf("expected a unitless metric error, got %v", err)
+	}
+
+	err = validateAlertConditionThresholdUnit("10%", "")
+	if err == nil || !regexp.MustCompile("only supported for metric conditions").MatchString(err.Error()) {
+		t.Fatalf("expected an error for units without a metric, got %v", err)
+	}
+}
+
+func TestValidateAlertConditionMetric(t *testing.T) {
+	if _, errs := validateAlertConditionMetric("apdex", "metric"); len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	if _, errs := validateAlertConditionMetric("apdexx", "metric"); len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+}
+
 // TODO: func_ TestAccNewRelicAlertCondition_Multi(t *testing.T) {
 
+func testAccNewRelicAlertConditionTermKey(term map[string]interface{}, field string) string {
+	hash := resourceNewRelicAlertConditionTermHash(term)
+	return fmt.Sprintf("term.%d.%s", hash, field)
+}
+
+// testAccCheckNewRelicAlertConditionTermAttr checks a field of the term with
+// the given priority, as the term hash depends on every field of the term.
+func testAccCheckNewRelicAlertConditionTermAttr(n string, priority string, field string, value string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		for k, v := range rs.Primary.Attributes {
+			parts := strings.Split(k, ".")
+			if len(parts) != 3 || parts[0] != "term" || parts[2] != "priority" || v != priority {
+				continue
+			}
+
+			key := fmt.Sprintf("term.%s.%s", parts[1], field)
+			if actual := rs.Primary.Attributes[key]; actual != value {
+				return fmt.Errorf("%s: Attribute '%s' expected %#v, got %#v", n, key, value, actual)
+			}
+
+			return nil
+		}
+
+		return fmt.Errorf("%s: no %s term found", n, priority)
+	}
+}
+
+// testAccCheckNewRelicAlertConditionEntity checks that the condition targets
+// the entity with the ID of the given resource.
+func testAccCheckNewRelicAlertConditionEntity(n string, entity string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[entity]
+		if !ok {
+			return fmt.Errorf("Not found: %s", entity)
+		}
+
+		id, err := strconv.Atoi(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		hash := schema.HashSchema(&schema.Schema{Type: schema.TypeInt})(id)
+
+		return resource.TestCheckResourceAttr(n, fmt.Sprintf("entities.%d", hash), rs.Primary.ID)(s)
+	}
+}
+
 func testAccCheckNewRelicAlertConditionDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*newrelic.Client)
+	client := testAccProvider.Meta().(*ProviderConfig).Client
 	for _, r := range s.RootModule().Resources {
 		if r.Type != "newrelic_alert_condition" {
 			continue
@@ -144,7 +1007,7 @@ func testAccCheckNewRelicAlertConditionExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("No alert condition ID is set")
 		}
 
-		client := testAccProvider.Meta().(*newrelic.Client)
+		client := testAccProvider.Meta().(*ProviderConfig).Client
 
 		ids, err := parseIDs(rs.Primary.ID, 2)
 		if err != nil {
@@ -215,6 +1078,7 @@ resource "newrelic_alert_condition" "foo" {
   type            = "apm_app_metric"
   entities        = ["${data.newrelic_application.app.id}"]
   metric          = "apdex"
+  enabled         = false
   runbook_url     = "https://bar.example.com"
   condition_scope = "application"
 
@@ -261,3 +1125,121 @@ resource "newrelic_alert_condition" "foo" {
 }
 
 // TODO: const testAccCheckNewRelicAlertConditionConfigMulti = `
+
+func testAccCheckNewRelicAlertConditionConfigEntityNames(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name            = "tf-test-%[1]s"
+  type            = "apm_app_metric"
+  entity_names    = ["%[2]s"]
+  metric          = "apdex"
+  condition_scope = "application"
+
+  term {
+    duration      = 5
+    operator      = "below"
+    priority      = "critical"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+}
+`, rName, testAccExpectedApplicationName)
+}
+
+func testAccCheckNewRelicAlertConditionConfigEntityNamesUnknown(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name            = "tf-test-%[1]s"
+  type            = "apm_app_metric"
+  entity_names    = ["tf-test-missing-%[1]s"]
+  metric          = "apdex"
+  condition_scope = "application"
+
+  term {
+    duration      = 5
+    operator      = "below"
+    priority      = "critical"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+}
+`, rName)
+}
+
+func testAccCheckNewRelicAlertConditionConfigEntityLabelSelector(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name            = "tf-test-%[1]s"
+  type            = "apm_app_metric"
+  metric          = "apdex"
+  condition_scope = "application"
+
+  entity_label_selector = {
+    tf-test-%[1]s = "payments"
+  }
+
+  term {
+    duration      = 5
+    operator      = "below"
+    priority      = "critical"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+}
+`, rName, testAccExpectedApplicationName)
+}
+
+func testAccCheckNewRelicAlertConditionConfigUnits(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name            = "tf-test-%[1]s"
+  type            = "apm_app_metric"
+  entities        = ["${data.newrelic_application.app.id}"]
+  metric          = "response_time_web"
+  condition_scope = "application"
+
+  critical {
+    duration      = "10m"
+    operator      = "above"
+    threshold     = "500ms"
+    time_function = "all"
+  }
+}
+`, rName, testAccExpectedApplicationName)
+}
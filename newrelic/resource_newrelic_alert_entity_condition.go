@@ -0,0 +1,186 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+var alertEntityConditionTypes = []string{
+	"Application",
+	"BrowserApplication",
+	"KeyTransaction",
+	"MobileApplication",
+	"Server",
+}
+
+func entityConditionExists(client *newrelic.Client, entityID int, entityType string, conditionID int) (bool, error) {
+	conditions, err := client.ListAlertEntityConditions(entityID, entityType)
+	if err != nil {
+		if err == newrelic.ErrNotFound {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	for _, condition := range conditions {
+		if condition.ID == conditionID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func resourceNewRelicAlertEntityCondition() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNewRelicAlertEntityConditionCreate,
+		Read:   resourceNewRelicAlertEntityConditionRead,
+		// Update: Not currently supported in API
+		Delete: resourceNewRelicAlertEntityConditionDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceNewRelicAlertEntityConditionImportState,
+		},
+		Schema: map[string]*schema.Schema{
+			"entity_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"condition_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"entity_type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "Application",
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice(alertEntityConditionTypes, false),
+			},
+		},
+	}
+}
+
+// The import ID is <entity_id>:<condition_id>, optionally followed by
+// :<entity_type> for entities that are not APM applications.
+func resourceNewRelicAlertEntityConditionImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	parts := strings.SplitN(d.Id(), ":", 3)
+	if len(parts) == 3 {
+		entityType := parts[2]
+		if _, errs := validation.StringInSlice(alertEntityConditionTypes, false)(entityType, "entity_type"); len(errs) > 0 {
+			return nil, errs[0]
+		}
+
+		d.Set("entity_type", entityType)
+		d.SetId(strings.Join(parts[:2], ":"))
+	}
+
+	if _, err := parseIDs(d.Id(), 2); err != nil {
+		return nil, fmt.Errorf("Invalid alert entity condition import ID %q, expected <entity_id>:<condition_id>[:<entity_type>]", d.Id())
+	}
+
+	return []*schema.ResourceData{d}, nil
+}
+
+func resourceNewRelicAlertEntityConditionCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	entityID := d.Get("entity_id").(int)
+	conditionID := d.Get("condition_id").(int)
+	entityType := d.Get("entity_type").(string)
+
+	serializedID := serializeIDs([]int{entityID, conditionID})
+
+	log.Printf("[INFO] Creating New Relic alert entity condition %s", serializedID)
+
+	exists, err := entityConditionExists(client, entityID, entityType, conditionID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		err = client.AddAlertEntityCondition(entityID, entityType, conditionID)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.SetId(serializedID)
+
+	return nil
+}
+
+func resourceNewRelicAlertEntityConditionRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	entityID := ids[0]
+	conditionID := ids[1]
+
+	entityType := d.Get("entity_type").(string)
+	if entityType == "" {
+		entityType = "Application"
+	}
+
+	log.Printf("[INFO] Reading New Relic alert entity condition %s", d.Id())
+
+	exists, err := entityConditionExists(client, entityID, entityType, conditionID)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		d.SetId("")
+		return nil
+	}
+
+	d.Set("entity_id", entityID)
+	d.Set("condition_id", conditionID)
+	d.Set("entity_type", entityType)
+
+	return nil
+}
+
+func resourceNewRelicAlertEntityConditionDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	entityID := ids[0]
+	conditionID := ids[1]
+	entityType := d.Get("entity_type").(string)
+
+	log.Printf("[INFO] Deleting New Relic alert entity condition %s", d.Id())
+
+	exists, err := entityConditionExists(client, entityID, entityType, conditionID)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		if err := client.DeleteAlertEntityCondition(entityID, entityType, conditionID); err != nil {
+			if err == newrelic.ErrNotFound {
+				return nil
+			}
+			return err
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
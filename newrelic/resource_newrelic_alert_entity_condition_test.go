@@ -0,0 +1,123 @@
+package newrelic
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccNewRelicAlertEntityCondition_Basic(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertEntityConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertEntityConditionConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertEntityConditionExists("newrelic_alert_entity_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_entity_condition.foo", "entity_type", "Application"),
+				),
+			},
+		},
+	})
+}
+
+func testAccCheckNewRelicAlertEntityConditionDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+	for _, r := range s.RootModule().Resources {
+		if r.Type != "newrelic_alert_entity_condition" {
+			continue
+		}
+
+		ids, err := parseIDs(r.Primary.ID, 2)
+		if err != nil {
+			return err
+		}
+
+		entityID := ids[0]
+		conditionID := ids[1]
+
+		exists, err := entityConditionExists(client, entityID, r.Primary.Attributes["entity_type"], conditionID)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return fmt.Errorf("Resource still exists")
+		}
+	}
+	return nil
+}
+
+func testAccCheckNewRelicAlertEntityConditionExists(n string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No resource ID is set")
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		ids, err := parseIDs(rs.Primary.ID, 2)
+		if err != nil {
+			return err
+		}
+
+		entityID := ids[0]
+		conditionID := ids[1]
+
+		exists, err := entityConditionExists(client, entityID, rs.Primary.Attributes["entity_type"], conditionID)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("Resource not found: %v", rs.Primary.ID)
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckNewRelicAlertEntityConditionConfig(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name                   = "tf-test-%[1]s"
+  type                   = "apm_app_metric"
+  metric                 = "apdex"
+  condition_scope        = "application"
+  ignore_remote_entities = true
+
+  term {
+    duration      = 5
+    operator      = "below"
+    priority      = "critical"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+}
+
+resource "newrelic_alert_entity_condition" "foo" {
+  entity_id    = "${data.newrelic_application.app.id}"
+  condition_id = "${element(split(":", newrelic_alert_condition.foo.id), 1)}"
+}
+`, rName, testAccExpectedApplicationName)
+}
@@ -0,0 +1,294 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/helper/validation"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+const externalServiceConditionType = "apm_external_service"
+
+var externalServiceConditionMetrics = []string{
+	"response_time_average",
+	"response_time_minimum_maximum",
+	"throughput",
+}
+
+func resourceNewRelicAlertExternalServiceCondition() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNewRelicAlertExternalServiceConditionCreate,
+		Read:   resourceNewRelicAlertExternalServiceConditionRead,
+		Update: resourceNewRelicAlertExternalServiceConditionUpdate,
+		Delete: resourceNewRelicAlertExternalServiceConditionDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		CustomizeDiff: resourceNewRelicAlertExternalServiceConditionCustomizeDiff,
+		Schema: map[string]*schema.Schema{
+			"policy_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"entities": {
+				Type:     schema.TypeList,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Required: true,
+				MinItems: 1,
+			},
+			"external_service_url": {
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"metric": {
+				Type:         schema.TypeString,
+				Required:     true,
+				ValidateFunc: validation.StringInSlice(externalServiceConditionMetrics, false),
+			},
+			"runbook_url": {
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"term": {
+				Type: schema.TypeList,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"duration": alertConditionDurationSchema(alertConditionDurations),
+						"operator": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "equal",
+							ValidateFunc: validation.StringInSlice([]string{"above", "below", "equal"}, false),
+						},
+						"priority": {
+							Type:         schema.TypeString,
+							Optional:     true,
+							Default:      "critical",
+							ValidateFunc: validation.StringInSlice([]string{"critical", "warning"}, false),
+						},
+						"threshold": alertConditionThresholdValueSchema(),
+						"time_function": {
+							Type:         schema.TypeString,
+							Required:     true,
+							ValidateFunc: validation.StringInSlice([]string{"all", "any"}, false),
+						},
+					},
+				},
+				Required: true,
+				MinItems: 1,
+			},
+		},
+	}
+}
+
+// resourceNewRelicAlertExternalServiceConditionCustomizeDiff rejects
+// threshold units that do not fit the metric at plan time.
+func resourceNewRelicAlertExternalServiceConditionCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if !d.NewValueKnown("metric") || !d.NewValueKnown("term") {
+		return nil
+	}
+
+	for _, termI := range d.Get("term").([]interface{}) {
+		threshold := termI.(map[string]interface{})["threshold"].(string)
+
+		// not known until apply
+		if threshold == "" {
+			continue
+		}
+
+		if err := validateAlertConditionThresholdUnit(threshold, d.Get("metric").(string)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func buildAlertExternalServiceConditionStruct(d *schema.ResourceData) (*newrelic.AlertExternalServiceCondition, error) {
+	entitySet := d.Get("entities").([]interface{})
+	entities := make([]string, len(entitySet))
+
+	for i, entity := range entitySet {
+		entities[i] = strconv.Itoa(entity.(int))
+	}
+
+	termSet := d.Get("term").([]interface{})
+	terms := make([]newrelic.AlertConditionTerm, len(termSet))
+
+	for i, termI := range termSet {
+		termM := termI.(map[string]interface{})
+
+		term, err := expandAlertConditionTerm(termM, termM["priority"].(string))
+		if err != nil {
+			return nil, err
+		}
+
+		terms[i] = term
+	}
+
+	condition := newrelic.AlertExternalServiceCondition{
+		Type:               externalServiceConditionType,
+		Name:               d.Get("name").(string),
+		Enabled:            true,
+		Entities:           entities,
+		ExternalServiceURL: d.Get("external_service_url").(string),
+		Metric:             d.Get("metric").(string),
+		Terms:              terms,
+		PolicyID:           d.Get("policy_id").(int),
+	}
+
+	if attr, ok := d.GetOk("runbook_url"); ok {
+		condition.RunbookURL = attr.(string)
+	}
+
+	return &condition, nil
+}
+
+func readAlertExternalServiceConditionStruct(condition *newrelic.AlertExternalServiceCondition, d *schema.ResourceData) error {
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	policyID := ids[0]
+
+	entities := make([]int, len(condition.Entities))
+	for i, entity := range condition.Entities {
+		v, err := strconv.ParseInt(entity, 10, 32)
+		if err != nil {
+			return err
+		}
+		entities[i] = int(v)
+	}
+
+	d.Set("policy_id", policyID)
+	d.Set("name", condition.Name)
+	d.Set("external_service_url", condition.ExternalServiceURL)
+	d.Set("metric", condition.Metric)
+	d.Set("runbook_url", condition.RunbookURL)
+	if err := d.Set("entities", entities); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting alert condition entities: %#v", err)
+	}
+
+	var terms []map[string]interface{}
+
+	for _, src := range condition.Terms {
+		dst := map[string]interface{}{
+			"duration":      strconv.Itoa(src.Duration),
+			"operator":      src.Operator,
+			"priority":      src.Priority,
+			"threshold":     formatAlertConditionThreshold(src.Threshold),
+			"time_function": src.TimeFunction,
+		}
+		terms = append(terms, dst)
+	}
+
+	if err := d.Set("term", terms); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting alert condition terms: %#v", err)
+	}
+
+	return nil
+}
+
+func resourceNewRelicAlertExternalServiceConditionCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildAlertExternalServiceConditionStruct(d)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Creating New Relic external service alert condition %s", condition.Name)
+
+	condition, err = client.CreateAlertExternalServiceCondition(*condition)
+	if err != nil {
+		return err
+	}
+
+	d.SetId(serializeIDs([]int{condition.PolicyID, condition.ID}))
+
+	return resourceNewRelicAlertExternalServiceConditionRead(d, meta)
+}
+
+func resourceNewRelicAlertExternalServiceConditionRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	log.Printf("[INFO] Reading New Relic external service alert condition %s", d.Id())
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	policyID := ids[0]
+	id := ids[1]
+
+	condition, err := client.GetAlertExternalServiceCondition(policyID, id)
+	if err != nil {
+		if err == newrelic.ErrNotFound {
+			d.SetId("")
+			return nil
+		}
+
+		return err
+	}
+
+	return readAlertExternalServiceConditionStruct(condition, d)
+}
+
+func resourceNewRelicAlertExternalServiceConditionUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildAlertExternalServiceConditionStruct(d)
+	if err != nil {
+		return err
+	}
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	policyID := ids[0]
+	id := ids[1]
+
+	condition.PolicyID = policyID
+	condition.ID = id
+
+	log.Printf("[INFO] Updating New Relic external service alert condition %d", id)
+
+	_, err = client.UpdateAlertExternalServiceCondition(*condition)
+	if err != nil {
+		return err
+	}
+
+	return resourceNewRelicAlertExternalServiceConditionRead(d, meta)
+}
+
+func resourceNewRelicAlertExternalServiceConditionDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return err
+	}
+
+	policyID := ids[0]
+	id := ids[1]
+
+	log.Printf("[INFO] Deleting New Relic external service alert condition %d", id)
+
+	if err := client.DeleteAlertExternalServiceCondition(policyID, id); err != nil {
+		return err
+	}
+
+	d.SetId("")
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package newrelic
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+)
+
+func TestAccNewRelicAlertExternalServiceCondition_Basic(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertExternalServiceConditionDestroy,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccCheckNewRelicAlertExternalServiceConditionConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertExternalServiceConditionExists("newrelic_alert_external_service_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "name", fmt.Sprintf("tf-test-%s", rName)),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "external_service_url", "example.com"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "metric", "response_time_average"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "runbook_url", "https://foo.example.com"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "entities.#", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.#", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.duration", "5"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.operator", "above"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.priority", "critical"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.threshold", "0.75"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.time_function", "all"),
+				),
+			},
+			{
+				Config: testAccCheckNewRelicAlertExternalServiceConditionConfigUpdated(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertExternalServiceConditionExists("newrelic_alert_external_service_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "name", fmt.Sprintf("tf-test-updated-%s", rName)),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "metric", "throughput"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "runbook_url", "https://bar.example.com"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.#", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.duration", "10"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.operator", "below"),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_external_service_condition.foo", "term.0.threshold", "10"),
+				),
+			},
+		},
+	})
+}
+
+func TestResourceNewRelicAlertExternalServiceConditionCustomizeDiff(t *testing.T) {
+	raw := func(metric string, threshold string) map[string]interface{} {
+		return map[string]interface{}{
+			"policy_id":            1,
+			"name":                 "foo",
+			"entities":             []interface{}{1},
+			"external_service_url": "api.example.com",
+			"metric":               metric,
+			"term": []interface{}{
+				map[string]interface{}{"duration": "5", "operator": "above", "threshold": threshold, "time_function": "all"},
+			},
+		}
+	}
+
+	if _, err := testResourceDiff(t, resourceNewRelicAlertExternalServiceCondition(), nil, raw("response_time_average", "250ms"), nil); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	_, err := testResourceDiff(t, resourceNewRelicAlertExternalServiceCondition(), nil, raw("throughput", "250ms"), nil)
+	if err == nil || !regexp.MustCompile("measures time, but metric throughput measures throughput").MatchString(err.Error()) {
+		t.Fatalf("expected a unit mismatch error, got %v", err)
+	}
+}
+
+func testAccCheckNewRelicAlertExternalServiceConditionDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+	for _, r := range s.RootModule().Resources {
+		if r.Type != "newrelic_alert_external_service_condition" {
+			continue
+		}
+
+		ids, err := parseIDs(r.Primary.ID, 2)
+		if err != nil {
+			return err
+		}
+
+		policyID := ids[0]
+		id := ids[1]
+
+		_, err = client.GetAlertExternalServiceCondition(policyID, id)
+		if err == nil {
+			return fmt.Errorf("External service alert condition still exists")
+		}
+
+	}
+	return nil
+}
+
+func testAccCheckNewRelicAlertExternalServiceConditionExists(n string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No alert condition ID is set")
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		ids, err := parseIDs(rs.Primary.ID, 2)
+		if err != nil {
+			return err
+		}
+
+		policyID := ids[0]
+		id := ids[1]
+
+		found, err := client.GetAlertExternalServiceCondition(policyID, id)
+		if err != nil {
+			return err
+		}
+
+		if found.ID != id {
+			return fmt.Errorf("Alert condition not found: %v - %v", id, found)
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckNewRelicAlertExternalServiceConditionConfig(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_external_service_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name                 = "tf-test-%[1]s"
+  entities             = ["${data.newrelic_application.app.id}"]
+  external_service_url = "example.com"
+  metric               = "response_time_average"
+  runbook_url          = "https://foo.example.com"
+
+  term {
+    duration      = 5
+    operator      = "above"
+    priority      = "critical"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+}
+`, rName, testAccExpectedApplicationName)
+}
+
+func testAccCheckNewRelicAlertExternalServiceConditionConfigUpdated(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+	name = "%[2]s"
+}
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_external_service_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name                 = "tf-test-updated-%[1]s"
+  entities             = ["${data.newrelic_application.app.id}"]
+  external_service_url = "example.com"
+  metric               = "throughput"
+  runbook_url          = "https://bar.example.com"
+
+  term {
+    duration      = 10
+    operator      = "below"
+    priority      = "critical"
+    threshold     = "10"
+    time_function = "all"
+  }
+}
+`, rName, testAccExpectedApplicationName)
+}
@@ -1,6 +1,7 @@
 package newrelic
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/hashicorp/terraform/helper/schema"
@@ -32,6 +33,9 @@ func resourceNewRelicAlertPolicyChannel() *schema.Resource {
 		Read:   resourceNewRelicAlertPolicyChannelRead,
 		// Update: Not currently supported in API
 		Delete: resourceNewRelicAlertPolicyChannelDelete,
+		Importer: &schema.ResourceImporter{
+			State: resourceNewRelicAlertPolicyChannelImportState,
+		},
 		Schema: map[string]*schema.Schema{
 			"policy_id": {
 				Type:     schema.TypeInt,
@@ -47,8 +51,40 @@ func resourceNewRelicAlertPolicyChannel() *schema.Resource {
 	}
 }
 
+func resourceNewRelicAlertPolicyChannelImportState(d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	client := meta.(*ProviderConfig).Client
+
+	ids, err := parseIDs(d.Id(), 2)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid alert policy channel import ID %q, expected <policy_id>:<channel_id>", d.Id())
+	}
+
+	policyID := ids[0]
+	channelID := ids[1]
+
+	if policyID <= 0 || channelID <= 0 {
+		return nil, fmt.Errorf("Invalid alert policy channel import ID %q, policy and channel IDs must be positive", d.Id())
+	}
+
+	log.Printf("[INFO] Importing New Relic alert policy channel %s", d.Id())
+
+	exists, err := policyChannelExists(client, policyID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("Alert channel %d is not attached to alert policy %d", channelID, policyID)
+	}
+
+	d.Set("policy_id", policyID)
+	d.Set("channel_id", channelID)
+
+	return []*schema.ResourceData{d}, nil
+}
+
 func resourceNewRelicAlertPolicyChannelCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	policyID := d.Get("policy_id").(int)
 	channelID := d.Get("channel_id").(int)
@@ -75,7 +111,7 @@ func resourceNewRelicAlertPolicyChannelCreate(d *schema.ResourceData, meta inter
 }
 
 func resourceNewRelicAlertPolicyChannelRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
@@ -104,7 +140,7 @@ func resourceNewRelicAlertPolicyChannelRead(d *schema.ResourceData, meta interfa
 }
 
 func resourceNewRelicAlertPolicyChannelDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
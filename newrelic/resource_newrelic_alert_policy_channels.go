@@ -0,0 +1,203 @@
+package newrelic
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/hashicorp/terraform/helper/schema"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+// policyChannelIDs returns the IDs of every channel currently linked to the
+// policy, including links created outside of Terraform.
+func policyChannelIDs(client *newrelic.Client, policyID int) ([]int, error) {
+	channels, err := client.ListAlertChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	var channelIDs []int
+
+	for _, channel := range channels {
+		for _, id := range channel.Links.PolicyIDs {
+			if id == policyID {
+				channelIDs = append(channelIDs, channel.ID)
+				break
+			}
+		}
+	}
+
+	return channelIDs, nil
+}
+
+func resourceNewRelicAlertPolicyChannels() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceNewRelicAlertPolicyChannelsCreate,
+		Read:   resourceNewRelicAlertPolicyChannelsRead,
+		Update: resourceNewRelicAlertPolicyChannelsUpdate,
+		Delete: resourceNewRelicAlertPolicyChannelsDelete,
+		Importer: &schema.ResourceImporter{
+			State: schema.ImportStatePassthrough,
+		},
+		Schema: map[string]*schema.Schema{
+			"policy_id": {
+				Type:     schema.TypeInt,
+				Required: true,
+				ForceNew: true,
+			},
+			"channel_ids": {
+				Type:     schema.TypeSet,
+				Elem:     &schema.Schema{Type: schema.TypeInt},
+				Optional: true,
+			},
+		},
+	}
+}
+
+// syncAlertPolicyChannels makes the channels linked to the policy exactly
+// match the configured channel_ids.
+func syncAlertPolicyChannels(client *newrelic.Client, d *schema.ResourceData, policyID int) error {
+	remoteIDs, err := policyChannelIDs(client, policyID)
+	if err != nil {
+		return err
+	}
+
+	remote := make(map[int]bool, len(remoteIDs))
+	for _, id := range remoteIDs {
+		remote[id] = true
+	}
+
+	wanted := make(map[int]bool)
+	var missing []int
+
+	for _, v := range d.Get("channel_ids").(*schema.Set).List() {
+		id := v.(int)
+		wanted[id] = true
+
+		if !remote[id] {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) > 0 {
+		log.Printf("[INFO] Adding channels %v to New Relic alert policy %d", missing, policyID)
+
+		if err := client.UpdateAlertPolicyChannels(policyID, missing); err != nil {
+			return err
+		}
+	}
+
+	for _, id := range remoteIDs {
+		if wanted[id] {
+			continue
+		}
+
+		log.Printf("[INFO] Removing channel %d from New Relic alert policy %d", id, policyID)
+
+		if err := client.DeleteAlertPolicyChannel(policyID, id); err != nil {
+			if err == newrelic.ErrNotFound {
+				continue
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func resourceNewRelicAlertPolicyChannelsCreate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	policyID := d.Get("policy_id").(int)
+
+	log.Printf("[INFO] Creating New Relic alert policy channels for policy %d", policyID)
+
+	if err := syncAlertPolicyChannels(client, d, policyID); err != nil {
+		return err
+	}
+
+	d.SetId(strconv.Itoa(policyID))
+
+	return resourceNewRelicAlertPolicyChannelsRead(d, meta)
+}
+
+func resourceNewRelicAlertPolicyChannelsRead(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	policyID, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Reading New Relic alert policy channels for policy %d", policyID)
+
+	if _, err := client.GetAlertPolicy(policyID); err != nil {
+		if err == newrelic.ErrNotFound {
+			d.SetId("")
+			return nil
+		}
+
+		return err
+	}
+
+	channelIDs, err := policyChannelIDs(client, policyID)
+	if err != nil {
+		return err
+	}
+
+	d.Set("policy_id", policyID)
+	if err := d.Set("channel_ids", channelIDs); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting alert policy channel IDs: %#v", err)
+	}
+
+	return nil
+}
+
+func resourceNewRelicAlertPolicyChannelsUpdate(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	policyID, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Updating New Relic alert policy channels for policy %d", policyID)
+
+	if err := syncAlertPolicyChannels(client, d, policyID); err != nil {
+		return err
+	}
+
+	return resourceNewRelicAlertPolicyChannelsRead(d, meta)
+}
+
+func resourceNewRelicAlertPolicyChannelsDelete(d *schema.ResourceData, meta interface{}) error {
+	client := meta.(*ProviderConfig).Client
+
+	policyID, err := strconv.Atoi(d.Id())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[INFO] Deleting New Relic alert policy channels for policy %d", policyID)
+
+	// As the resource is authoritative, every link is removed, including those
+	// made outside of Terraform since the last refresh.
+	channelIDs, err := policyChannelIDs(client, policyID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range channelIDs {
+		if err := client.DeleteAlertPolicyChannel(policyID, id); err != nil {
+			if err == newrelic.ErrNotFound {
+				continue
+			}
+			return err
+		}
+	}
+
+	d.SetId("")
+
+	return nil
+}
@@ -0,0 +1,357 @@
+package newrelic
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/terraform/helper/acctest"
+	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/terraform"
+	newrelic "github.com/paultyng/go-newrelic/api"
+)
+
+func TestAccNewRelicAlertPolicyChannels_Basic(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertPolicyChannelsDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 1),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_policy_channels.foo", "channel_ids.#", "1"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfigUpdated(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 2),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_policy_channels.foo", "channel_ids.#", "2"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfigEmpty(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 0),
+					resource.TestCheckResourceAttr(
+						"newrelic_alert_policy_channels.foo", "channel_ids.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicAlertPolicyChannels_DeleteRemovesUnmanaged(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertPolicyChannelsDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 1),
+				),
+			},
+			// removing the resource unlinks every channel from the policy,
+			// including the one linked out-of-band
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfigRemoved(rName),
+				PreConfig: func() {
+					testAccNewRelicAlertPolicyChannelsAttachUnmanaged(t, fmt.Sprintf("tf-test-%s", rName))
+				},
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsCleared("newrelic_alert_policy.foo"),
+					testAccNewRelicAlertPolicyChannelsDeleteUnmanaged(fmt.Sprintf("tf-test-%s", rName)),
+				),
+			},
+		},
+	})
+}
+
+// testAccCheckNewRelicAlertPolicyChannelsCleared checks that no channel is
+// linked to the policy.
+func testAccCheckNewRelicAlertPolicyChannelsCleared(n string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		policyID, err := strconv.Atoi(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		channelIDs, err := policyChannelIDs(client, policyID)
+		if err != nil {
+			return err
+		}
+
+		if len(channelIDs) > 0 {
+			return fmt.Errorf("Alert policy %d still has channels %v", policyID, channelIDs)
+		}
+
+		return nil
+	}
+}
+
+func TestAccNewRelicAlertPolicyChannels_RemovesUnmanaged(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicAlertPolicyChannelsDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfigUpdated(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 2),
+				),
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicAlertPolicyChannelsConfigUpdated(rName),
+				PreConfig: func() {
+					// link a channel out-of-band, which the next apply should remove
+					testAccNewRelicAlertPolicyChannelsAttachUnmanaged(t, fmt.Sprintf("tf-test-%s", rName))
+				},
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicAlertPolicyChannelsExists("newrelic_alert_policy_channels.foo", 2),
+					testAccNewRelicAlertPolicyChannelsDeleteUnmanaged(fmt.Sprintf("tf-test-%s", rName)),
+				),
+			},
+		},
+	})
+}
+
+// testAccNewRelicAlertPolicyChannelsUnmanaged returns the channel created by
+// testAccNewRelicAlertPolicyChannelsAttachUnmanaged for the policy, or nil.
+func testAccNewRelicAlertPolicyChannelsUnmanaged(client *newrelic.Client, name string) (*newrelic.AlertChannel, error) {
+	channels, err := client.ListAlertChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range channels {
+		if channel.Name == fmt.Sprintf("%s-unmanaged", name) {
+			return &channel, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func testAccNewRelicAlertPolicyChannelsDeleteUnmanaged(name string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		channel, err := testAccNewRelicAlertPolicyChannelsUnmanaged(client, name)
+		if err != nil {
+			return err
+		}
+
+		if channel == nil {
+			return fmt.Errorf("Alert channel %s-unmanaged not found", name)
+		}
+
+		return client.DeleteAlertChannel(channel.ID)
+	}
+}
+
+func testAccNewRelicAlertPolicyChannelsAttachUnmanaged(t *testing.T, name string) {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+
+	policies, err := client.ListAlertPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, policy := range policies {
+		if policy.Name != name {
+			continue
+		}
+
+		channel, err := client.CreateAlertChannel(newrelic.AlertChannel{
+			Name: fmt.Sprintf("%s-unmanaged", name),
+			Type: "email",
+			Configuration: map[string]interface{}{
+				"recipients": "baz@example.com",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := client.UpdateAlertPolicyChannels(policy.ID, []int{channel.ID}); err != nil {
+			t.Fatal(err)
+		}
+
+		return
+	}
+
+	t.Fatalf("Alert policy %s not found", name)
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsDestroy(s *terraform.State) error {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+	for _, r := range s.RootModule().Resources {
+		if r.Type != "newrelic_alert_policy_channels" {
+			continue
+		}
+
+		policyID, err := strconv.Atoi(r.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		channelIDs, err := policyChannelIDs(client, policyID)
+		if err != nil {
+			return err
+		}
+
+		if len(channelIDs) > 0 {
+			return fmt.Errorf("Alert policy %d still has channels %v", policyID, channelIDs)
+		}
+	}
+
+	for _, r := range s.RootModule().Resources {
+		if r.Type != "newrelic_alert_policy" {
+			continue
+		}
+
+		channel, err := testAccNewRelicAlertPolicyChannelsUnmanaged(client, r.Primary.Attributes["name"])
+		if err != nil {
+			return err
+		}
+
+		if channel != nil {
+			return fmt.Errorf("Alert channel %s still exists", channel.Name)
+		}
+	}
+	return nil
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsExists(n string, count int) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+		if rs.Primary.ID == "" {
+			return fmt.Errorf("No resource ID is set")
+		}
+
+		client := testAccProvider.Meta().(*ProviderConfig).Client
+
+		policyID, err := strconv.Atoi(rs.Primary.ID)
+		if err != nil {
+			return err
+		}
+
+		channelIDs, err := policyChannelIDs(client, policyID)
+		if err != nil {
+			return err
+		}
+
+		if len(channelIDs) != count {
+			return fmt.Errorf("Expected %d channels on policy %d, got %v", count, policyID, channelIDs)
+		}
+
+		return nil
+	}
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsConfig(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_channel" "foo" {
+  name = "tf-test-%[1]s"
+  type = "email"
+
+  configuration = {
+    recipients              = "foo@example.com"
+    include_json_attachment = "1"
+  }
+}
+
+resource "newrelic_alert_policy_channels" "foo" {
+  policy_id   = "${newrelic_alert_policy.foo.id}"
+  channel_ids = ["${newrelic_alert_channel.foo.id}"]
+}
+`, rName)
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsConfigUpdated(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_channel" "foo" {
+  name = "tf-test-%[1]s"
+  type = "email"
+
+  configuration = {
+    recipients              = "foo@example.com"
+    include_json_attachment = "1"
+  }
+}
+
+resource "newrelic_alert_channel" "bar" {
+  name = "tf-test-bar-%[1]s"
+  type = "email"
+
+  configuration = {
+    recipients              = "bar@example.com"
+    include_json_attachment = "0"
+  }
+}
+
+resource "newrelic_alert_policy_channels" "foo" {
+  policy_id   = "${newrelic_alert_policy.foo.id}"
+  channel_ids = ["${newrelic_alert_channel.foo.id}", "${newrelic_alert_channel.bar.id}"]
+}
+`, rName)
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsConfigEmpty(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_policy_channels" "foo" {
+  policy_id   = "${newrelic_alert_policy.foo.id}"
+  channel_ids = []
+}
+`, rName)
+}
+
+func testAccCheckNewRelicAlertPolicyChannelsConfigRemoved(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_alert_channel" "foo" {
+  name = "tf-test-%[1]s"
+  type = "email"
+
+  configuration = {
+    recipients              = "foo@example.com"
+    include_json_attachment = "1"
+  }
+}
+`, rName)
+}
@@ -8,11 +8,11 @@ import (
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
 	"github.com/hashicorp/terraform/terraform"
-	newrelic "github.com/paultyng/go-newrelic/api"
 )
 
 func TestAccNewRelicAlertPolicy_Basic(t *testing.T) {
 	rName := acctest.RandString(5)
+	var policyID string
 	resource.Test(t, resource.TestCase{
 		PreCheck:     func() { testAccPreCheck(t) },
 		Providers:    testAccProviders,
@@ -22,6 +22,7 @@ func TestAccNewRelicAlertPolicy_Basic(t *testing.T) {
 				Config: testAccCheckNewRelicAlertPolicyConfig(rName),
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckNewRelicAlertPolicyExists("newrelic_alert_policy.foo"),
+					testAccCheckNewRelicAlertPolicyID("newrelic_alert_policy.foo", &policyID),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_policy.foo", "name", fmt.Sprintf("tf-test-%s", rName)),
 					resource.TestCheckResourceAttr(
@@ -32,6 +33,7 @@ func TestAccNewRelicAlertPolicy_Basic(t *testing.T) {
 				Config: testAccCheckNewRelicAlertPolicyConfigUpdated(rName),
 				Check: resource.ComposeTestCheckFunc(
 					testAccCheckNewRelicAlertPolicyExists("newrelic_alert_policy.foo"),
+					testAccCheckNewRelicAlertPolicyID("newrelic_alert_policy.foo", &policyID),
 					resource.TestCheckResourceAttr(
 						"newrelic_alert_policy.foo", "name", fmt.Sprintf("tf-test-updated-%s", rName)),
 					resource.TestCheckResourceAttr(
@@ -43,7 +45,7 @@ func TestAccNewRelicAlertPolicy_Basic(t *testing.T) {
 }
 
 func testAccCheckNewRelicAlertPolicyDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*newrelic.Client)
+	client := testAccProvider.Meta().(*ProviderConfig).Client
 	for _, r := range s.RootModule().Resources {
 		if r.Type != "newrelic_alert_policy" {
 			continue
@@ -64,6 +66,28 @@ func testAccCheckNewRelicAlertPolicyDestroy(s *terraform.State) error {
 	return nil
 }
 
+// testAccCheckNewRelicAlertPolicyID records the policy ID on first use and
+// fails if a later step sees a different ID, i.e. the policy was replaced.
+func testAccCheckNewRelicAlertPolicyID(n string, id *string) resource.TestCheckFunc {
+	return func(s *terraform.State) error {
+		rs, ok := s.RootModule().Resources[n]
+		if !ok {
+			return fmt.Errorf("Not found: %s", n)
+		}
+
+		if *id == "" {
+			*id = rs.Primary.ID
+			return nil
+		}
+
+		if rs.Primary.ID != *id {
+			return fmt.Errorf("Policy was replaced: expected ID %s, got %s", *id, rs.Primary.ID)
+		}
+
+		return nil
+	}
+}
+
 func testAccCheckNewRelicAlertPolicyExists(n string) resource.TestCheckFunc {
 	return func(s *terraform.State) error {
 		rs, ok := s.RootModule().Resources[n]
@@ -74,7 +98,7 @@ func testAccCheckNewRelicAlertPolicyExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("No policy ID is set")
 		}
 
-		client := testAccProvider.Meta().(*newrelic.Client)
+		client := testAccProvider.Meta().(*ProviderConfig).Client
 
 		id, err := strconv.ParseInt(rs.Primary.ID, 10, 32)
 		if err != nil {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"log"
+	"sort"
 	"strconv"
 
 	"github.com/hashicorp/terraform/helper/hashcode"
@@ -87,9 +88,64 @@ func resourceNewRelicDashboard() *schema.Resource {
 						},
 						// TODO: Move this to a set/map?
 						"nrql": &schema.Schema{
+							Type:         schema.TypeString,
+							Optional:     true,
+							ValidateFunc: validateNrql,
+						},
+						"source": &schema.Schema{
 							Type:     schema.TypeString,
 							Optional: true,
 						},
+						"sources": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"filters": &schema.Schema{
+							Type:     schema.TypeMap,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"entity_ids": &schema.Schema{
+							Type:     schema.TypeSet,
+							Optional: true,
+							Elem:     &schema.Schema{Type: schema.TypeInt},
+						},
+						"metric": &schema.Schema{
+							Type:     schema.TypeList,
+							Optional: true,
+							Elem: &schema.Resource{
+								Schema: map[string]*schema.Schema{
+									"name": &schema.Schema{
+										Type:     schema.TypeString,
+										Required: true,
+									},
+									"values": &schema.Schema{
+										Type:     schema.TypeList,
+										Optional: true,
+										Elem:     &schema.Schema{Type: schema.TypeString},
+									},
+								},
+							},
+						},
+						"duration": &schema.Schema{
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
+						"end_time": &schema.Schema{
+							Type:     schema.TypeInt,
+							Optional: true,
+						},
+						"order_by": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"limit": &schema.Schema{
+							Type:         schema.TypeInt,
+							Optional:     true,
+							ValidateFunc: validation.IntAtLeast(1),
+						},
 					},
 				},
 			},
@@ -113,13 +169,207 @@ func resourceNewRelicDashboardWidgetsHash(v interface{}) int {
 	buf.WriteString(fmt.Sprintf("%d-%d-%d-%d-%s-%s-%s-%s",
 		row, column, width, height, nrql, title, viz, notes))
 
+	// The fields of other visualizations are only hashed when set, so the
+	// hashes of NRQL widgets are unchanged.
+	if v, ok := m["source"].(string); ok && v != "" {
+		buf.WriteString(fmt.Sprintf("-source:%s", v))
+	}
+
+	if sources := expandDashboardWidgetSources(m); len(sources) > 0 {
+		buf.WriteString(fmt.Sprintf("-%v", sources))
+	}
+
+	if filters := expandDashboardWidgetFilters(m); len(filters) > 0 {
+		keys := make([]string, 0, len(filters))
+		for k := range filters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			buf.WriteString(fmt.Sprintf("-%s=%s", k, filters[k]))
+		}
+	}
+
+	if ids := dashboardWidgetEntityIDs(m); len(ids) > 0 {
+		buf.WriteString(fmt.Sprintf("-%v", ids))
+	}
+
+	for _, metric := range expandDashboardWidgetMetrics(m) {
+		buf.WriteString(fmt.Sprintf("-%s%v", metric.Name, metric.Values))
+	}
+
+	for _, k := range []string{"duration", "end_time", "limit"} {
+		if v, ok := m[k].(int); ok && v != 0 {
+			buf.WriteString(fmt.Sprintf("-%s:%d", k, v))
+		}
+	}
+
+	if v, ok := m["order_by"].(string); ok && v != "" {
+		buf.WriteString(fmt.Sprintf("-order_by:%s", v))
+	}
+
 	return hashcode.String(buf.String())
 }
 
+// dashboardWidgetDataFieldNames are the data fields of a widget, in the
+// order they are validated.
+var dashboardWidgetDataFieldNames = []string{
+	"nrql", "source", "sources", "filters",
+	"entity_ids", "metric", "duration", "end_time", "order_by", "limit",
+}
+
+// dashboardWidgetDataFields lists the data fields valid for each
+// visualization not based on an NRQL query, and whether they are required.
+// All other visualizations require nrql and take no other data fields.
+var dashboardWidgetDataFields = map[string]map[string]bool{
+	"application_breakdown": {
+		"entity_ids": true, "metric": false, "duration": false, "end_time": false, "order_by": false, "limit": false,
+	},
+	"metric_line_chart": {
+		"entity_ids": true, "metric": true, "duration": true, "end_time": false, "order_by": false, "limit": false,
+	},
+	"markdown": {
+		"source": true,
+	},
+	"event_feed": {
+		"nrql": false, "filters": false,
+	},
+	"inventory": {
+		"sources": true, "filters": false,
+	},
+}
+
+// dashboardWidgetEntityIDs returns the sorted entity_ids of a widget.
+func dashboardWidgetEntityIDs(m map[string]interface{}) []int {
+	var ids []int
+
+	if set, ok := m["entity_ids"].(*schema.Set); ok {
+		for _, id := range set.List() {
+			ids = append(ids, id.(int))
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+func expandDashboardWidgetSources(m map[string]interface{}) []string {
+	var sources []string
+
+	list, _ := m["sources"].([]interface{})
+	for _, v := range list {
+		sources = append(sources, v.(string))
+	}
+
+	return sources
+}
+
+func expandDashboardWidgetFilters(m map[string]interface{}) map[string]string {
+	filters := map[string]string{}
+
+	raw, _ := m["filters"].(map[string]interface{})
+	for k, v := range raw {
+		filters[k] = v.(string)
+	}
+
+	if len(filters) == 0 {
+		return nil
+	}
+
+	return filters
+}
+
+func expandDashboardWidgetMetrics(m map[string]interface{}) []newrelic.DashboardWidgetDataMetric {
+	var metrics []newrelic.DashboardWidgetDataMetric
+
+	list, _ := m["metric"].([]interface{})
+	for _, metricI := range list {
+		metricM := metricI.(map[string]interface{})
+
+		metric := newrelic.DashboardWidgetDataMetric{
+			Name: metricM["name"].(string),
+		}
+
+		values, _ := metricM["values"].([]interface{})
+		for _, v := range values {
+			metric.Values = append(metric.Values, v.(string))
+		}
+
+		metrics = append(metrics, metric)
+	}
+
+	return metrics
+}
+
+func flattenDashboardWidgetMetrics(metrics []newrelic.DashboardWidgetDataMetric) []interface{} {
+	var list []interface{}
+
+	for _, metric := range metrics {
+		values := make([]interface{}, 0, len(metric.Values))
+		for _, v := range metric.Values {
+			values = append(values, v)
+		}
+
+		list = append(list, map[string]interface{}{
+			"name":   metric.Name,
+			"values": values,
+		})
+	}
+
+	return list
+}
+
+// validateDashboardWidget checks that a widget has the data fields its
+// visualization requires, and none of those of other visualizations.
+func validateDashboardWidget(widget newrelic.DashboardWidget) error {
+	data := widget.Data[0]
+	viz := widget.Visualization
+
+	set := map[string]bool{
+		"nrql":       data.NRQL != "",
+		"source":     data.Source != "",
+		"sources":    len(data.Sources) > 0,
+		"filters":    len(data.Filters) > 0,
+		"entity_ids": len(data.EntityIds) > 0,
+		"metric":     len(data.Metrics) > 0,
+		"duration":   data.Duration != 0,
+		"end_time":   data.EndTime != 0,
+		"order_by":   data.OrderBy != "",
+		"limit":      data.Limit != 0,
+	}
+
+	fields, ok := dashboardWidgetDataFields[viz]
+	if !ok {
+		fields = map[string]bool{"nrql": true}
+	}
+
+	for _, field := range dashboardWidgetDataFieldNames {
+		required, valid := fields[field]
+
+		if set[field] && !valid {
+			return fmt.Errorf("widget %q: %s is not valid for %s widgets", widget.Presentation.Title, field, viz)
+		}
+
+		if required && !set[field] {
+			return fmt.Errorf("widget %q: %s is required for %s widgets", widget.Presentation.Title, field, viz)
+		}
+	}
+
+	// Event feeds show either the events of an NRQL query or Infrastructure
+	// events matching the filters.
+	if viz == "event_feed" && set["nrql"] == set["filters"] {
+		return fmt.Errorf("widget %q: exactly one of nrql or filters is required for event_feed widgets", widget.Presentation.Title)
+	}
+
+	return nil
+}
+
 // Assemble the *newrelic.Dashboard variable.
 //
 // Used by the newrelic_dashboard Create and Update functions.
-func expandDashboard(d *schema.ResourceData) *newrelic.Dashboard {
+func expandDashboard(d *schema.ResourceData) (*newrelic.Dashboard, error) {
 	metadata := newrelic.DashboardMetadata{
 		Version: 1,
 	}
@@ -151,23 +401,37 @@ func expandDashboard(d *schema.ResourceData) *newrelic.Dashboard {
 				Height: w["height"].(int),
 			}
 
-			// TODO: Support non-NRQL Widgets
 			widgetData := []newrelic.DashboardWidgetData{
 				{
-					NRQL: w["nrql"].(string),
+					NRQL:      w["nrql"].(string),
+					Source:    w["source"].(string),
+					Sources:   expandDashboardWidgetSources(w),
+					Filters:   expandDashboardWidgetFilters(w),
+					EntityIds: dashboardWidgetEntityIDs(w),
+					Metrics:   expandDashboardWidgetMetrics(w),
+					Duration:  w["duration"].(int),
+					EndTime:   w["end_time"].(int),
+					OrderBy:   w["order_by"].(string),
+					Limit:     w["limit"].(int),
 				},
 			}
 
-			dashboard.Widgets = append(dashboard.Widgets, newrelic.DashboardWidget{
+			widget := newrelic.DashboardWidget{
 				Visualization: w["visualization"].(string),
 				Layout:        widgetLayout,
 				Presentation:  widgetPresentation,
 				Data:          widgetData,
-			})
+			}
+
+			if err := validateDashboardWidget(widget); err != nil {
+				return nil, err
+			}
+
+			dashboard.Widgets = append(dashboard.Widgets, widget)
 		}
 	}
 
-	return &dashboard
+	return &dashboard, nil
 }
 
 // Unpack the *newrelic.Dashboard variable and set resource data.
@@ -193,9 +457,34 @@ func flattenDashboard(dashboard *newrelic.Dashboard, d *schema.ResourceData) err
 		values["width"] = widget.Layout.Width
 		values["height"] = widget.Layout.Height
 
-		// TODO: Support non-NRQL Widgets
 		if len(widget.Data) > 0 {
-			values["nrql"] = widget.Data[0].NRQL
+			data := widget.Data[0]
+
+			entityIDs := &schema.Set{F: schema.HashSchema(&schema.Schema{Type: schema.TypeInt})}
+			for _, id := range data.EntityIds {
+				entityIDs.Add(id)
+			}
+
+			sources := make([]interface{}, 0, len(data.Sources))
+			for _, source := range data.Sources {
+				sources = append(sources, source)
+			}
+
+			filters := map[string]interface{}{}
+			for k, v := range data.Filters {
+				filters[k] = v
+			}
+
+			values["nrql"] = data.NRQL
+			values["source"] = data.Source
+			values["sources"] = sources
+			values["filters"] = filters
+			values["entity_ids"] = entityIDs
+			values["metric"] = flattenDashboardWidgetMetrics(data.Metrics)
+			values["duration"] = data.Duration
+			values["end_time"] = data.EndTime
+			values["order_by"] = data.OrderBy
+			values["limit"] = data.Limit
 		}
 		widgetSet.Add(values)
 	}
@@ -208,11 +497,14 @@ func flattenDashboard(dashboard *newrelic.Dashboard, d *schema.ResourceData) err
 }
 
 func resourceNewRelicDashboardCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	dashboard := expandDashboard(d)
+	client := meta.(*ProviderConfig).Client
+	dashboard, err := expandDashboard(d)
+	if err != nil {
+		return err
+	}
 	log.Printf("[INFO] Creating New Relic dashboard: %s", dashboard.Title)
 
-	dashboard, err := client.CreateDashboard(*dashboard)
+	dashboard, err = client.CreateDashboard(*dashboard)
 	if err != nil {
 		return err
 	}
@@ -223,7 +515,7 @@ func resourceNewRelicDashboardCreate(d *schema.ResourceData, meta interface{}) e
 }
 
 func resourceNewRelicDashboardRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	log.Printf("[INFO] Reading New Relic dashboard %s", d.Id())
 
@@ -246,8 +538,11 @@ func resourceNewRelicDashboardRead(d *schema.ResourceData, meta interface{}) err
 }
 
 func resourceNewRelicDashboardUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	dashboard := expandDashboard(d)
+	client := meta.(*ProviderConfig).Client
+	dashboard, err := expandDashboard(d)
+	if err != nil {
+		return err
+	}
 
 	id, err := strconv.Atoi(d.Id())
 	if err != nil {
@@ -266,7 +561,7 @@ func resourceNewRelicDashboardUpdate(d *schema.ResourceData, meta interface{}) e
 }
 
 func resourceNewRelicDashboardDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	id, err := strconv.Atoi(d.Id())
 	if err != nil {
@@ -2,11 +2,14 @@ package newrelic
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
 	"strconv"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 	newrelic "github.com/paultyng/go-newrelic/api"
 )
@@ -87,8 +90,278 @@ func TestAccNewRelicDashboard_Basic(t *testing.T) {
 	})
 }
 
+func TestAccNewRelicDashboard_MetricWidgets(t *testing.T) {
+	rName := fmt.Sprintf("tf-test-%s", acctest.RandString(5))
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicDashboardDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicDashboardMetricWidgetsConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicDashboardExists("newrelic_dashboard.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_dashboard.foo", "widget.#", "2"),
+				),
+			},
+			// Metric widgets read back without changes
+			resource.TestStep{
+				Config:   testAccCheckNewRelicDashboardMetricWidgetsConfig(rName),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestAccNewRelicDashboard_MarkdownWidget(t *testing.T) {
+	rName := fmt.Sprintf("tf-test-%s", acctest.RandString(5))
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicDashboardDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicDashboardMarkdownWidgetConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicDashboardExists("newrelic_dashboard.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_dashboard.foo", "widget.#", "2"),
+				),
+			},
+			// Markdown widgets read back without changes
+			resource.TestStep{
+				Config:   testAccCheckNewRelicDashboardMarkdownWidgetConfig(rName),
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+func TestExpandDashboardMetricWidget(t *testing.T) {
+	raw := map[string]interface{}{
+		"title": "foo",
+		"widget": []interface{}{
+			map[string]interface{}{
+				"title":         "Response time",
+				"visualization": "metric_line_chart",
+				"row":           1,
+				"column":        1,
+				"entity_ids":    []interface{}{456, 123},
+				"metric": []interface{}{
+					map[string]interface{}{"name": "HttpDispatcher", "values": []interface{}{"average_call_time", "call_count"}},
+				},
+				"duration": 1800000,
+				"end_time": 1520000000000,
+				"order_by": "average_call_time",
+				"limit":    10,
+			},
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNewRelicDashboard().Schema, raw)
+
+	dashboard, err := expandDashboard(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []newrelic.DashboardWidgetData{
+		{
+			EntityIds: []int{123, 456},
+			Metrics: []newrelic.DashboardWidgetDataMetric{
+				{Name: "HttpDispatcher", Values: []string{"average_call_time", "call_count"}},
+			},
+			Duration: 1800000,
+			EndTime:  1520000000000,
+			OrderBy:  "average_call_time",
+			Limit:    10,
+		},
+	}
+	if !reflect.DeepEqual(dashboard.Widgets[0].Data, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, dashboard.Widgets[0].Data)
+	}
+
+	read := schema.TestResourceDataRaw(t, resourceNewRelicDashboard().Schema, map[string]interface{}{})
+	if err := flattenDashboard(dashboard, read); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	roundTrip, err := expandDashboard(read)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(roundTrip.Widgets, dashboard.Widgets) {
+		t.Fatalf("expected %#v, got %#v", dashboard.Widgets, roundTrip.Widgets)
+	}
+
+	if read.Get("widget").(*schema.Set).Difference(d.Get("widget").(*schema.Set)).Len() != 0 {
+		t.Fatalf("expected widget hashes %v, got %v", d.Get("widget"), read.Get("widget"))
+	}
+}
+
+func TestExpandDashboardInfrastructureWidgets(t *testing.T) {
+	raw := map[string]interface{}{
+		"title": "foo",
+		"widget": []interface{}{
+			map[string]interface{}{
+				"title":         "Runbook",
+				"visualization": "markdown",
+				"row":           1,
+				"column":        1,
+				"source":        "# Runbook\n\nRestart the service.",
+			},
+			map[string]interface{}{
+				"title":         "Apache config",
+				"visualization": "inventory",
+				"row":           1,
+				"column":        2,
+				"sources":       []interface{}{"config/apache"},
+				"filters":       map[string]interface{}{"environment": "production"},
+			},
+		},
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNewRelicDashboard().Schema, raw)
+
+	dashboard, err := expandDashboard(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	read := schema.TestResourceDataRaw(t, resourceNewRelicDashboard().Schema, map[string]interface{}{})
+	if err := flattenDashboard(dashboard, read); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	roundTrip, err := expandDashboard(read)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	data := map[string]newrelic.DashboardWidgetData{}
+	for _, widget := range roundTrip.Widgets {
+		data[widget.Visualization] = widget.Data[0]
+	}
+
+	expected := map[string]newrelic.DashboardWidgetData{
+		"markdown":  {Source: "# Runbook\n\nRestart the service."},
+		"inventory": {Sources: []string{"config/apache"}, Filters: map[string]string{"environment": "production"}},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, data)
+	}
+
+	if read.Get("widget").(*schema.Set).Difference(d.Get("widget").(*schema.Set)).Len() != 0 {
+		t.Fatalf("expected widget hashes %v, got %v", d.Get("widget"), read.Get("widget"))
+	}
+}
+
+func TestValidateDashboardWidget(t *testing.T) {
+	widget := func(viz string, data newrelic.DashboardWidgetData) newrelic.DashboardWidget {
+		return newrelic.DashboardWidget{
+			Visualization: viz,
+			Presentation:  newrelic.DashboardWidgetPresentation{Title: "foo"},
+			Data:          []newrelic.DashboardWidgetData{data},
+		}
+	}
+
+	metrics := []newrelic.DashboardWidgetDataMetric{{Name: "Apdex", Values: []string{"score"}}}
+
+	cases := []struct {
+		widget      newrelic.DashboardWidget
+		expectedErr *regexp.Regexp
+	}{
+		{
+			widget: widget("billboard", newrelic.DashboardWidgetData{NRQL: "SELECT count(*) FROM Transaction"}),
+		},
+		{
+			widget: widget("application_breakdown", newrelic.DashboardWidgetData{EntityIds: []int{1}}),
+		},
+		{
+			widget: widget("metric_line_chart", newrelic.DashboardWidgetData{EntityIds: []int{1}, Metrics: metrics, Duration: 1800000}),
+		},
+		{
+			widget: widget("markdown", newrelic.DashboardWidgetData{Source: "# Runbook"}),
+		},
+		{
+			widget: widget("event_feed", newrelic.DashboardWidgetData{Filters: map[string]string{"eventType": "ProcessSample"}}),
+		},
+		{
+			widget: widget("event_feed", newrelic.DashboardWidgetData{NRQL: "SELECT * FROM Transaction"}),
+		},
+		{
+			widget: widget("inventory", newrelic.DashboardWidgetData{Sources: []string{"config/apache"}}),
+		},
+		{
+			widget:      widget("markdown", newrelic.DashboardWidgetData{}),
+			expectedErr: regexp.MustCompile("source is required for markdown widgets"),
+		},
+		{
+			widget:      widget("markdown", newrelic.DashboardWidgetData{Source: "# Runbook", NRQL: "SELECT count(*) FROM Transaction"}),
+			expectedErr: regexp.MustCompile("nrql is not valid for markdown widgets"),
+		},
+		{
+			widget:      widget("event_feed", newrelic.DashboardWidgetData{}),
+			expectedErr: regexp.MustCompile("exactly one of nrql or filters is required for event_feed widgets"),
+		},
+		{
+			widget:      widget("inventory", newrelic.DashboardWidgetData{Filters: map[string]string{"environment": "production"}}),
+			expectedErr: regexp.MustCompile("sources is required for inventory widgets"),
+		},
+		{
+			widget:      widget("inventory", newrelic.DashboardWidgetData{Sources: []string{"config/apache"}, EntityIds: []int{1}}),
+			expectedErr: regexp.MustCompile("entity_ids is not valid for inventory widgets"),
+		},
+		{
+			widget:      widget("billboard", newrelic.DashboardWidgetData{NRQL: "SELECT count(*) FROM Transaction", Source: "# Runbook"}),
+			expectedErr: regexp.MustCompile("source is not valid for billboard widgets"),
+		},
+		{
+			widget:      widget("billboard", newrelic.DashboardWidgetData{}),
+			expectedErr: regexp.MustCompile("nrql is required for billboard widgets"),
+		},
+		{
+			widget:      widget("billboard", newrelic.DashboardWidgetData{NRQL: "SELECT count(*) FROM Transaction", Limit: 10}),
+			expectedErr: regexp.MustCompile("limit is not valid for billboard widgets"),
+		},
+		{
+			widget:      widget("application_breakdown", newrelic.DashboardWidgetData{}),
+			expectedErr: regexp.MustCompile("entity_ids is required for application_breakdown widgets"),
+		},
+		{
+			widget:      widget("metric_line_chart", newrelic.DashboardWidgetData{EntityIds: []int{1}, Duration: 1800000}),
+			expectedErr: regexp.MustCompile("metric is required for metric_line_chart widgets"),
+		},
+		{
+			widget:      widget("metric_line_chart", newrelic.DashboardWidgetData{EntityIds: []int{1}, Metrics: metrics}),
+			expectedErr: regexp.MustCompile("duration is required for metric_line_chart widgets"),
+		},
+		{
+			widget:      widget("metric_line_chart", newrelic.DashboardWidgetData{NRQL: "SELECT count(*) FROM Transaction", EntityIds: []int{1}, Metrics: metrics, Duration: 1800000}),
+			expectedErr: regexp.MustCompile("nrql is not valid for metric_line_chart widgets"),
+		},
+	}
+
+	for i, c := range cases {
+		err := validateDashboardWidget(c.widget)
+
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
 func testAccCheckNewRelicDashboardDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*newrelic.Client)
+	client := testAccProvider.Meta().(*ProviderConfig).Client
 	for _, r := range s.RootModule().Resources {
 		if r.Type != "newrelic_dashboard" {
 			continue
@@ -119,7 +392,7 @@ func testAccCheckNewRelicDashboardExists(n string) resource.TestCheckFunc {
 			return fmt.Errorf("No dashboard ID is set")
 		}
 
-		client := testAccProvider.Meta().(*newrelic.Client)
+		client := testAccProvider.Meta().(*ProviderConfig).Client
 
 		id, err := strconv.ParseInt(rs.Primary.ID, 10, 32)
 		if err != nil {
@@ -212,3 +485,62 @@ resource "newrelic_dashboard" "foo" {
 }
 `, rName)
 }
+
+// The test application for these widgets is created in provider_test.go
+func testAccCheckNewRelicDashboardMetricWidgetsConfig(rName string) string {
+	return fmt.Sprintf(`
+data "newrelic_application" "app" {
+  name = "%s"
+}
+
+resource "newrelic_dashboard" "foo" {
+  title = "%s"
+
+  widget {
+    title         = "Breakdown"
+    visualization = "application_breakdown"
+    row           = 1
+    column        = 1
+    entity_ids    = ["${data.newrelic_application.app.id}"]
+  }
+
+  widget {
+    title         = "Response time"
+    visualization = "metric_line_chart"
+    row           = 1
+    column        = 2
+    entity_ids    = ["${data.newrelic_application.app.id}"]
+    duration      = 1800000
+
+    metric {
+      name   = "HttpDispatcher"
+      values = ["average_call_time"]
+    }
+  }
+}
+`, testAccExpectedApplicationName, rName)
+}
+
+func testAccCheckNewRelicDashboardMarkdownWidgetConfig(rName string) string {
+	return fmt.Sprintf(`
+resource "newrelic_dashboard" "foo" {
+  title = "%s"
+
+  widget {
+    title         = "Runbook"
+    visualization = "markdown"
+    row           = 1
+    column        = 1
+    source        = "# Runbook\n\nRestart the service."
+  }
+
+  widget {
+    title         = "Transactions"
+    visualization = "billboard"
+    row           = 1
+    column        = 2
+    nrql          = "SELECT count(*) FROM Transaction"
+  }
+}
+`, rName)
+}
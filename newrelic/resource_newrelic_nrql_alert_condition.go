@@ -9,6 +9,117 @@ import (
 	newrelic "github.com/paultyng/go-newrelic/api"
 )
 
+var nrqlAlertConditionDurations = []int{1, 2, 3, 4, 5, 10, 15, 30, 60, 120}
+
+// validateNrqlAlertCondition checks the fields whose valid values depend on
+// the condition type.
+func validateNrqlAlertCondition(condition *newrelic.AlertNrqlCondition) error {
+	if condition.Type != "baseline" && condition.BaselineDirection != "" {
+		return fmt.Errorf("baseline_direction is only valid for baseline conditions")
+	}
+
+	if condition.Type != "outlier" {
+		if condition.ExpectedGroups != 0 {
+			return fmt.Errorf("expected_groups is only valid for outlier conditions")
+		}
+
+		if condition.IgnoreOverlap {
+			return fmt.Errorf("ignore_overlap is only valid for outlier conditions")
+		}
+	}
+
+	switch condition.Type {
+	case "baseline":
+		if condition.BaselineDirection == "" {
+			return fmt.Errorf("baseline_direction is required for baseline conditions")
+		}
+	case "outlier":
+		q, err := parseNrql(condition.Nrql.Query)
+		if err != nil {
+			return fmt.Errorf("invalid NRQL: %s", err)
+		}
+
+		if q.clause("FACET") == nil {
+			return fmt.Errorf("the query of an outlier condition must have a FACET clause")
+		}
+	default:
+		return nil
+	}
+
+	if condition.ValueFunction != "single_value" {
+		return fmt.Errorf("value_function %q is not valid for %s conditions", condition.ValueFunction, condition.Type)
+	}
+
+	// Baseline and outlier thresholds are deviations from the expected value;
+	// baseline_direction decides on which side of it baseline thresholds apply.
+	for _, term := range condition.Terms {
+		if term.Operator != "above" {
+			return fmt.Errorf("operator %q is not valid for %s conditions, expected \"above\"", term.Operator, condition.Type)
+		}
+
+		if term.Threshold <= 0 {
+			return fmt.Errorf("threshold %v is not valid for %s conditions, expected a number of standard deviations greater than 0", term.Threshold, condition.Type)
+		}
+	}
+
+	return nil
+}
+
+func nrqlAlertConditionQuerySchema() *schema.Schema {
+	return &schema.Schema{
+		Type:     schema.TypeList,
+		Required: true,
+		MinItems: 1,
+		MaxItems: 1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"query": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validateNrqlAlertQuery,
+				},
+				"since_value": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"1", "2", "3", "4", "5"}, false),
+				},
+			},
+		},
+	}
+}
+
+func nrqlAlertConditionTermSchema() *schema.Schema {
+	return &schema.Schema{
+		Type: schema.TypeList,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"duration": alertConditionDurationSchema(nrqlAlertConditionDurations),
+				"operator": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "equal",
+					ValidateFunc: validation.StringInSlice([]string{"above", "below", "equal"}, false),
+				},
+				"priority": {
+					Type:         schema.TypeString,
+					Optional:     true,
+					Default:      "critical",
+					ValidateFunc: validation.StringInSlice([]string{"critical", "warning"}, false),
+				},
+				"threshold": alertConditionThresholdValueSchema(),
+				"time_function": {
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice([]string{"all", "any"}, false),
+				},
+			},
+		},
+		Optional:      true,
+		MinItems:      1,
+		ConflictsWith: []string{"critical", "warning"},
+	}
+}
+
 func resourceNewRelicNrqlAlertCondition() *schema.Resource {
 
 	return &schema.Resource{
@@ -19,6 +130,7 @@ func resourceNewRelicNrqlAlertCondition() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			State: schema.ImportStatePassthrough,
 		},
+		CustomizeDiff: resourceNewRelicNrqlAlertConditionCustomizeDiff,
 		Schema: map[string]*schema.Schema{
 			"policy_id": {
 				Type:     schema.TypeInt,
@@ -38,61 +150,60 @@ func resourceNewRelicNrqlAlertCondition() *schema.Resource {
 				Optional: true,
 				Default:  true,
 			},
-			"nrql": {
-				Type:     schema.TypeList,
-				Required: true,
-				MinItems: 1,
-				MaxItems: 1,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"query": {
-							Type:     schema.TypeString,
-							Required: true,
-						},
-						"since_value": {
-							Type:         schema.TypeString,
-							Required:     true,
-							ValidateFunc: validation.StringInSlice([]string{"1", "2", "3", "4", "5"}, false),
-						},
-					},
-				},
+			"type": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "static",
+				ForceNew:     true,
+				ValidateFunc: validation.StringInSlice([]string{"static", "baseline", "outlier"}, false),
 			},
-			"term": {
-				Type: schema.TypeList,
-				Elem: &schema.Resource{
-					Schema: map[string]*schema.Schema{
-						"duration": {
-							Type:         schema.TypeInt,
-							Required:     true,
-							ValidateFunc: intInSlice([]int{1, 2, 3, 4, 5, 10, 15, 30, 60, 120}),
-						},
-						"operator": {
-							Type:         schema.TypeString,
-							Optional:     true,
-							Default:      "equal",
-							ValidateFunc: validation.StringInSlice([]string{"above", "below", "equal"}, false),
-						},
-						"priority": {
-							Type:         schema.TypeString,
-							Optional:     true,
-							Default:      "critical",
-							ValidateFunc: validation.StringInSlice([]string{"critical", "warning"}, false),
-						},
-						"threshold": {
-							Type:         schema.TypeFloat,
-							Required:     true,
-							ValidateFunc: float64Gte(0.0),
-						},
-						"time_function": {
-							Type:         schema.TypeString,
-							Required:     true,
-							ValidateFunc: validation.StringInSlice([]string{"all", "any"}, false),
-						},
-					},
-				},
-				Required: true,
-				MinItems: 1,
+			"baseline_direction": {
+				Type:         schema.TypeString,
+				Optional:     true,
+				ValidateFunc: validation.StringInSlice([]string{"upper_only", "lower_only", "upper_and_lower"}, false),
+			},
+			"expected_groups": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntAtLeast(1),
+			},
+			"ignore_overlap": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"violation_time_limit_seconds": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				Computed:      true,
+				ValidateFunc:  intInSlice([]int{3600, 7200, 14400, 28800, 43200, 86400}),
+				ConflictsWith: []string{"violation_close_timer"},
 			},
+			"violation_close_timer": {
+				Type:          schema.TypeInt,
+				Optional:      true,
+				ValidateFunc:  intInSlice([]int{1, 2, 4, 8, 12, 24}),
+				ConflictsWith: []string{"violation_time_limit_seconds"},
+			},
+			"expiration_duration": {
+				Type:         schema.TypeInt,
+				Optional:     true,
+				ValidateFunc: validation.IntBetween(30, 172800),
+			},
+			"open_violation_on_expiration": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"close_violations_on_expiration": {
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"nrql":     nrqlAlertConditionQuerySchema(),
+			"term":     nrqlAlertConditionTermSchema(),
+			"critical": alertConditionThresholdSchema(nrqlAlertConditionDurations),
+			"warning":  alertConditionThresholdSchema(nrqlAlertConditionDurations),
 			"value_function": {
 				Type:         schema.TypeString,
 				Optional:     true,
@@ -103,22 +214,63 @@ func resourceNewRelicNrqlAlertCondition() *schema.Resource {
 	}
 }
 
-func buildNrqlAlertConditionStruct(d *schema.ResourceData) *newrelic.AlertNrqlCondition {
-	termSet := d.Get("term").([]interface{})
-	terms := make([]newrelic.AlertConditionTerm, len(termSet))
+// resourceNewRelicNrqlAlertConditionCustomizeDiff rejects combinations of
+// fields that are valid on their own but not together, so they fail at plan
+// time.
+func resourceNewRelicNrqlAlertConditionCustomizeDiff(d *schema.ResourceDiff, meta interface{}) error {
+	if err := validateAlertConditionTermsDiff(d, ""); err != nil {
+		return err
+	}
 
-	for i, termI := range termSet {
-		termM := termI.(map[string]interface{})
+	if d.NewValueKnown("nrql.0.query") {
+		var sinceValue string
+		if d.NewValueKnown("nrql.0.since_value") {
+			sinceValue = d.Get("nrql.0.since_value").(string)
+		}
 
-		terms[i] = newrelic.AlertConditionTerm{
-			Duration:     termM["duration"].(int),
-			Operator:     termM["operator"].(string),
-			Priority:     termM["priority"].(string),
-			Threshold:    termM["threshold"].(float64),
-			TimeFunction: termM["time_function"].(string),
+		if q, err := parseNrql(d.Get("nrql.0.query").(string)); err == nil {
+			if err := nrqlAlertQuerySinceError(q, sinceValue); err != nil {
+				return err
+			}
 		}
 	}
 
+	fields := []string{"type", "baseline_direction", "expected_groups", "ignore_overlap", "value_function", "nrql.0.query"}
+	for _, k := range fields {
+		if !d.NewValueKnown(k) {
+			log.Printf("[DEBUG] Not validating New Relic NRQL alert condition until %s is known", k)
+			return nil
+		}
+	}
+
+	if !alertConditionTermsKnown(d) {
+		return nil
+	}
+
+	terms, err := expandAlertConditionTerms(d)
+	if err != nil {
+		return err
+	}
+
+	condition := newrelic.AlertNrqlCondition{
+		Type:              d.Get("type").(string),
+		Terms:             terms,
+		Nrql:              newrelic.AlertNrqlQuery{Query: d.Get("nrql.0.query").(string)},
+		ValueFunction:     d.Get("value_function").(string),
+		BaselineDirection: d.Get("baseline_direction").(string),
+		ExpectedGroups:    d.Get("expected_groups").(int),
+		IgnoreOverlap:     d.Get("ignore_overlap").(bool),
+	}
+
+	return validateNrqlAlertCondition(&condition)
+}
+
+func buildNrqlAlertConditionStruct(d *schema.ResourceData) (*newrelic.AlertNrqlCondition, error) {
+	terms, err := expandAlertConditionTerms(d)
+	if err != nil {
+		return nil, err
+	}
+
 	query := newrelic.AlertNrqlQuery{}
 
 	if nrqlQuery, ok := d.GetOk("nrql.0.query"); ok {
@@ -130,19 +282,44 @@ func buildNrqlAlertConditionStruct(d *schema.ResourceData) *newrelic.AlertNrqlCo
 	}
 
 	condition := newrelic.AlertNrqlCondition{
-		Name:          d.Get("name").(string),
-		Enabled:       d.Get("enabled").(bool),
-		Terms:         terms,
-		PolicyID:      d.Get("policy_id").(int),
-		Nrql:          query,
-		ValueFunction: d.Get("value_function").(string),
+		Type:              d.Get("type").(string),
+		Name:              d.Get("name").(string),
+		Enabled:           d.Get("enabled").(bool),
+		Terms:             terms,
+		PolicyID:          d.Get("policy_id").(int),
+		Nrql:              query,
+		ValueFunction:     d.Get("value_function").(string),
+		BaselineDirection: d.Get("baseline_direction").(string),
+		ExpectedGroups:    d.Get("expected_groups").(int),
+		IgnoreOverlap:     d.Get("ignore_overlap").(bool),
+
+		ViolationTimeLimitSeconds: d.Get("violation_time_limit_seconds").(int),
+		ViolationCloseTimer:       d.Get("violation_close_timer").(int),
+	}
+
+	openOnExpiration := d.Get("open_violation_on_expiration").(bool)
+	closeOnExpiration := d.Get("close_violations_on_expiration").(bool)
+
+	if duration := d.Get("expiration_duration").(int); duration > 0 {
+		condition.Expiration = &newrelic.AlertNrqlExpiration{
+			ExpirationDuration:          duration,
+			OpenViolationOnExpiration:   openOnExpiration,
+			CloseViolationsOnExpiration: closeOnExpiration,
+		}
+	} else if openOnExpiration || closeOnExpiration {
+		return nil, fmt.Errorf("expiration_duration is required with open_violation_on_expiration or close_violations_on_expiration")
 	}
 
 	if attr, ok := d.GetOk("runbook_url"); ok {
 		condition.RunbookURL = attr.(string)
 	}
 
-	return &condition
+	// value_function only applies to static conditions
+	if condition.Type != "static" {
+		condition.ValueFunction = ""
+	}
+
+	return &condition, nil
 }
 
 func readNrqlAlertConditionStruct(condition *newrelic.AlertNrqlCondition, d *schema.ResourceData) error {
@@ -157,37 +334,63 @@ func readNrqlAlertConditionStruct(condition *newrelic.AlertNrqlCondition, d *sch
 	d.Set("name", condition.Name)
 	d.Set("runbook_url", condition.RunbookURL)
 	d.Set("enabled", condition.Enabled)
-	d.Set("value_function", condition.ValueFunction)
-	d.Set("nrql.0.Query", condition.Nrql.Query)
-	d.Set("nrql.0.SinceValue", condition.Nrql.SinceValue)
-
-	var terms []map[string]interface{}
-
-	for _, src := range condition.Terms {
-		dst := map[string]interface{}{
-			"duration":      src.Duration,
-			"operator":      src.Operator,
-			"priority":      src.Priority,
-			"threshold":     src.Threshold,
-			"time_function": src.TimeFunction,
-		}
-		terms = append(terms, dst)
+	d.Set("baseline_direction", condition.BaselineDirection)
+	d.Set("expected_groups", condition.ExpectedGroups)
+	d.Set("ignore_overlap", condition.IgnoreOverlap)
+	d.Set("violation_time_limit_seconds", condition.ViolationTimeLimitSeconds)
+	d.Set("violation_close_timer", condition.ViolationCloseTimer)
+
+	if condition.Expiration != nil {
+		d.Set("expiration_duration", condition.Expiration.ExpirationDuration)
+		d.Set("open_violation_on_expiration", condition.Expiration.OpenViolationOnExpiration)
+		d.Set("close_violations_on_expiration", condition.Expiration.CloseViolationsOnExpiration)
+	} else {
+		d.Set("expiration_duration", 0)
+		d.Set("open_violation_on_expiration", false)
+		d.Set("close_violations_on_expiration", false)
+	}
+
+	if condition.Type != "" {
+		d.Set("type", condition.Type)
+	} else {
+		d.Set("type", "static")
+	}
+
+	// value_function is not returned for conditions it does not apply to
+	if condition.ValueFunction != "" {
+		d.Set("value_function", condition.ValueFunction)
+	} else {
+		d.Set("value_function", "single_value")
+	}
+
+	nrql := []interface{}{
+		map[string]interface{}{
+			"query":       condition.Nrql.Query,
+			"since_value": condition.Nrql.SinceValue,
+		},
 	}
 
-	if err := d.Set("term", terms); err != nil {
-		return fmt.Errorf("[DEBUG] Error setting alert condition terms: %#v", err)
+	if err := d.Set("nrql", nrql); err != nil {
+		return fmt.Errorf("[DEBUG] Error setting NRQL alert condition query: %#v", err)
+	}
+
+	if err := flattenAlertConditionTerms(d, condition.Terms); err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func resourceNewRelicNrqlAlertConditionCreate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	condition := buildNrqlAlertConditionStruct(d)
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildNrqlAlertConditionStruct(d)
+	if err != nil {
+		return err
+	}
 
 	log.Printf("[INFO] Creating New Relic NRQL alert condition %s", condition.Name)
 
-	condition, err := client.CreateAlertNrqlCondition(*condition)
+	condition, err = client.CreateAlertNrqlCondition(*condition)
 	if err != nil {
 		return err
 	}
@@ -198,7 +401,7 @@ func resourceNewRelicNrqlAlertConditionCreate(d *schema.ResourceData, meta inter
 }
 
 func resourceNewRelicNrqlAlertConditionRead(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	log.Printf("[INFO] Reading New Relic NRQL alert condition %s", d.Id())
 
@@ -224,8 +427,11 @@ func resourceNewRelicNrqlAlertConditionRead(d *schema.ResourceData, meta interfa
 }
 
 func resourceNewRelicNrqlAlertConditionUpdate(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
-	condition := buildNrqlAlertConditionStruct(d)
+	client := meta.(*ProviderConfig).Client
+	condition, err := buildNrqlAlertConditionStruct(d)
+	if err != nil {
+		return err
+	}
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
@@ -249,7 +455,7 @@ func resourceNewRelicNrqlAlertConditionUpdate(d *schema.ResourceData, meta inter
 }
 
 func resourceNewRelicNrqlAlertConditionDelete(d *schema.ResourceData, meta interface{}) error {
-	client := meta.(*newrelic.Client)
+	client := meta.(*ProviderConfig).Client
 
 	ids, err := parseIDs(d.Id(), 2)
 	if err != nil {
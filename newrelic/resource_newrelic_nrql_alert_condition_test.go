@@ -2,10 +2,13 @@ package newrelic
 
 import (
 	"fmt"
+	"reflect"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform/helper/acctest"
 	"github.com/hashicorp/terraform/helper/resource"
+	"github.com/hashicorp/terraform/helper/schema"
 	"github.com/hashicorp/terraform/terraform"
 	newrelic "github.com/paultyng/go-newrelic/api"
 )
@@ -81,10 +84,478 @@ func TestAccNewRelicNrqlAlertCondition_Basic(t *testing.T) {
 	})
 }
 
+func TestAccNewRelicNrqlAlertCondition_QueryDrift(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicNrqlAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfig(rName),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+				),
+			},
+			resource.TestStep{
+				PreConfig: func() {
+					// change the query out-of-band, which the next plan should revert
+					testAccNewRelicNrqlAlertConditionSetQuery(t, fmt.Sprintf("tf-test-%s", rName), "SELECT count(*) FROM ComputeSample")
+				},
+				Config:             testAccCheckNewRelicNrqlAlertConditionConfig(rName),
+				PlanOnly:           true,
+				ExpectNonEmptyPlan: true,
+			},
+		},
+	})
+}
+
+func testAccNewRelicNrqlAlertConditionSetQuery(t *testing.T, name string, query string) {
+	client := testAccProvider.Meta().(*ProviderConfig).Client
+
+	policies, err := client.ListAlertPolicies()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, policy := range policies {
+		if policy.Name != name {
+			continue
+		}
+
+		conditions, err := client.ListAlertNrqlConditions(policy.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, condition := range conditions {
+			condition.PolicyID = policy.ID
+			condition.Nrql.Query = query
+
+			if _, err := client.UpdateAlertNrqlCondition(condition); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		return
+	}
+
+	t.Fatalf("alert policy %q not found", name)
+}
+
+func TestAccNewRelicNrqlAlertCondition_CriticalWarning(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicNrqlAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigCriticalWarning(rName, "0.85"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "critical.#", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "critical.0.threshold", "0.75"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "warning.#", "1"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "warning.0.threshold", "0.85"),
+					resource.TestCheckNoResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "term.#"),
+				),
+			},
+			resource.TestStep{
+				Config:      testAccCheckNewRelicNrqlAlertConditionConfigCriticalWarning(rName, "0.65"),
+				ExpectError: regexp.MustCompile("warning threshold 0.65 must be above critical threshold 0.75"),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicNrqlAlertCondition_Baseline(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicNrqlAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigBaseline(rName, "upper_only"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "type", "baseline"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "baseline_direction", "upper_only"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "critical.0.threshold", "3"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigBaseline(rName, "upper_and_lower"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "baseline_direction", "upper_and_lower"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicNrqlAlertCondition_Outlier(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicNrqlAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigOutlier(rName, "SELECT average(duration) FROM Transaction FACET host"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "type", "outlier"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "expected_groups", "2"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "ignore_overlap", "true"),
+				),
+			},
+			resource.TestStep{
+				Config:      testAccCheckNewRelicNrqlAlertConditionConfigOutlier(rName, "SELECT average(duration) FROM Transaction"),
+				ExpectError: regexp.MustCompile("must have a FACET clause"),
+			},
+		},
+	})
+}
+
+func TestAccNewRelicNrqlAlertCondition_Expiration(t *testing.T) {
+	rName := acctest.RandString(5)
+	resource.Test(t, resource.TestCase{
+		PreCheck:     func() { testAccPreCheck(t) },
+		Providers:    testAccProviders,
+		CheckDestroy: testAccCheckNewRelicNrqlAlertConditionDestroy,
+		Steps: []resource.TestStep{
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigExpiration(rName, "true"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "violation_time_limit_seconds", "3600"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "expiration_duration", "300"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "open_violation_on_expiration", "true"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "close_violations_on_expiration", "false"),
+				),
+			},
+			resource.TestStep{
+				Config: testAccCheckNewRelicNrqlAlertConditionConfigExpiration(rName, "false"),
+				Check: resource.ComposeTestCheckFunc(
+					testAccCheckNewRelicNrqlAlertConditionExists("newrelic_nrql_alert_condition.foo"),
+					resource.TestCheckResourceAttr(
+						"newrelic_nrql_alert_condition.foo", "open_violation_on_expiration", "false"),
+				),
+			},
+		},
+	})
+}
+
+func TestBuildNrqlAlertConditionStruct_expiration(t *testing.T) {
+	raw := map[string]interface{}{
+		"policy_id": 1,
+		"name":      "foo",
+		"nrql": []interface{}{
+			map[string]interface{}{"query": "SELECT count(*) FROM Transaction", "since_value": "3"},
+		},
+		"critical": []interface{}{
+			map[string]interface{}{"duration": "5", "operator": "above", "threshold": "1", "time_function": "all"},
+		},
+		"open_violation_on_expiration": true,
+	}
+
+	d := schema.TestResourceDataRaw(t, resourceNewRelicNrqlAlertCondition().Schema, raw)
+	if _, err := buildNrqlAlertConditionStruct(d); err == nil || !regexp.MustCompile("expiration_duration is required").MatchString(err.Error()) {
+		t.Fatalf("expected an error without expiration_duration, got %v", err)
+	}
+
+	raw["expiration_duration"] = 300
+	d = schema.TestResourceDataRaw(t, resourceNewRelicNrqlAlertCondition().Schema, raw)
+
+	condition, err := buildNrqlAlertConditionStruct(d)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := &newrelic.AlertNrqlExpiration{ExpirationDuration: 300, OpenViolationOnExpiration: true}
+	if !reflect.DeepEqual(condition.Expiration, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, condition.Expiration)
+	}
+}
+
+func TestResourceNewRelicNrqlAlertConditionCustomizeDiff(t *testing.T) {
+	term := func(operator string, threshold string) map[string]interface{} {
+		return map[string]interface{}{"duration": "5", "operator": operator, "threshold": threshold, "time_function": "all"}
+	}
+
+	cases := []struct {
+		raw         map[string]interface{}
+		expectedErr *regexp.Regexp
+	}{
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("below", "1")},
+				"warning":  []interface{}{term("below", "2")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("below", "1")},
+				"warning":  []interface{}{term("below", "0.5")},
+			},
+			expectedErr: regexp.MustCompile("must be above critical threshold 1"),
+		},
+		{
+			raw: map[string]interface{}{
+				"term": []interface{}{term("above", "1"), term("above", "2")},
+			},
+			expectedErr: regexp.MustCompile("only one critical term is allowed"),
+		},
+		{
+			raw: map[string]interface{}{
+				"critical": []interface{}{term("above", "10rpm")},
+			},
+			expectedErr: regexp.MustCompile("only supported for metric conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":               "baseline",
+				"baseline_direction": "upper_and_lower",
+				"critical":           []interface{}{term("above", "3")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"type":     "baseline",
+				"critical": []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("baseline_direction is required for baseline conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"baseline_direction": "lower_only",
+				"critical":           []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("baseline_direction is only valid for baseline conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":               "baseline",
+				"baseline_direction": "lower_only",
+				"critical":           []interface{}{term("below", "3")},
+			},
+			expectedErr: regexp.MustCompile("operator \"below\" is not valid for baseline conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":               "baseline",
+				"baseline_direction": "lower_only",
+				"critical":           []interface{}{term("above", "0")},
+			},
+			expectedErr: regexp.MustCompile("threshold 0 is not valid for baseline conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":            "outlier",
+				"expected_groups": 2,
+				"query":           "SELECT average(duration) FROM Transaction FACET host",
+				"critical":        []interface{}{term("above", "3")},
+			},
+		},
+		{
+			raw: map[string]interface{}{
+				"type":     "outlier",
+				"critical": []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("the query of an outlier condition must have a FACET clause"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":           "outlier",
+				"value_function": "sum",
+				"query":          "SELECT average(duration) FROM Transaction FACET host",
+				"critical":       []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("value_function \"sum\" is not valid for outlier conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"type":               "baseline",
+				"baseline_direction": "upper_only",
+				"value_function":     "sum",
+				"critical":           []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("value_function \"sum\" is not valid for baseline conditions"),
+		},
+		{
+			raw: map[string]interface{}{
+				"expected_groups": 2,
+				"critical":        []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile("expected_groups is only valid for outlier conditions"),
+		},
+		// checked once the query is known
+		{
+			raw: map[string]interface{}{
+				"type":     "outlier",
+				"query":    "${var.unknown}",
+				"critical": []interface{}{term("above", "3")},
+			},
+		},
+		// checked once the direction is known
+		{
+			raw: map[string]interface{}{
+				"type":               "baseline",
+				"baseline_direction": "${var.unknown}",
+				"critical":           []interface{}{term("above", "3")},
+			},
+		},
+		// since_value is "3"
+		{
+			raw: map[string]interface{}{
+				"query":    "SELECT count(*) FROM Transaction SINCE 3 minutes ago",
+				"critical": []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile(`remove it as since_value is already "3"`),
+		},
+		{
+			raw: map[string]interface{}{
+				"query":    "SELECT count(*) FROM Transaction SINCE 5 minutes ago",
+				"critical": []interface{}{term("above", "3")},
+			},
+			expectedErr: regexp.MustCompile(`SINCE 5 minutes ago does not match since_value = "3"`),
+		},
+	}
+
+	for i, c := range cases {
+		query := "SELECT count(*) FROM Transaction"
+
+		raw := map[string]interface{}{
+			"policy_id": 1,
+			"name":      "foo",
+		}
+		for k, v := range c.raw {
+			if k == "query" {
+				query = v.(string)
+				continue
+			}
+			raw[k] = v
+		}
+		raw["nrql"] = []interface{}{
+			map[string]interface{}{"query": query, "since_value": "3"},
+		}
+
+		_, err := testResourceDiff(t, resourceNewRelicNrqlAlertCondition(), nil, raw, nil)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
+func TestValidateNrqlAlertCondition(t *testing.T) {
+	term := newrelic.AlertConditionTerm{Duration: 5, Operator: "above", Priority: "critical", Threshold: 3, TimeFunction: "all"}
+	termBelow := term
+	termBelow.Operator = "below"
+
+	cases := []struct {
+		condition   newrelic.AlertNrqlCondition
+		expectedErr *regexp.Regexp
+	}{
+		{
+			condition: newrelic.AlertNrqlCondition{Type: "static", ValueFunction: "sum", Terms: []newrelic.AlertConditionTerm{termBelow}},
+		},
+		{
+			condition: newrelic.AlertNrqlCondition{Type: "baseline", BaselineDirection: "upper_only", ValueFunction: "single_value", Terms: []newrelic.AlertConditionTerm{term}},
+		},
+		{
+			condition:   newrelic.AlertNrqlCondition{Type: "static", BaselineDirection: "upper_only", ValueFunction: "single_value"},
+			expectedErr: regexp.MustCompile("baseline_direction is only valid for baseline conditions"),
+		},
+		{
+			condition:   newrelic.AlertNrqlCondition{Type: "baseline", ValueFunction: "single_value", Terms: []newrelic.AlertConditionTerm{term}},
+			expectedErr: regexp.MustCompile("baseline_direction is required"),
+		},
+		{
+			condition:   newrelic.AlertNrqlCondition{Type: "baseline", BaselineDirection: "lower_only", ValueFunction: "single_value", Terms: []newrelic.AlertConditionTerm{termBelow}},
+			expectedErr: regexp.MustCompile("operator \"below\" is not valid for baseline conditions"),
+		},
+		{
+			condition:   newrelic.AlertNrqlCondition{Type: "baseline", BaselineDirection: "lower_only", ValueFunction: "sum", Terms: []newrelic.AlertConditionTerm{term}},
+			expectedErr: regexp.MustCompile("value_function \"sum\" is not valid"),
+		},
+		{
+			condition: newrelic.AlertNrqlCondition{
+				Type:           "outlier",
+				ExpectedGroups: 2,
+				ValueFunction:  "single_value",
+				Nrql:           newrelic.AlertNrqlQuery{Query: "SELECT average(duration) FROM Transaction facet host"},
+				Terms:          []newrelic.AlertConditionTerm{term},
+			},
+		},
+		{
+			condition: newrelic.AlertNrqlCondition{
+				Type:          "outlier",
+				ValueFunction: "single_value",
+				Nrql:          newrelic.AlertNrqlQuery{Query: "SELECT average(duration) FROM Transaction"},
+				Terms:         []newrelic.AlertConditionTerm{term},
+			},
+			expectedErr: regexp.MustCompile("must have a FACET clause"),
+		},
+		{
+			condition: newrelic.AlertNrqlCondition{
+				Type:          "outlier",
+				ValueFunction: "sum",
+				Nrql:          newrelic.AlertNrqlQuery{Query: "SELECT average(duration) FROM Transaction FACET host"},
+				Terms:         []newrelic.AlertConditionTerm{term},
+			},
+			expectedErr: regexp.MustCompile("value_function \"sum\" is not valid for outlier conditions"),
+		},
+		{
+			condition:   newrelic.AlertNrqlCondition{Type: "static", ExpectedGroups: 2, ValueFunction: "single_value"},
+			expectedErr: regexp.MustCompile("expected_groups is only valid for outlier conditions"),
+		},
+	}
+
+	for i, c := range cases {
+		err := validateNrqlAlertCondition(&c.condition)
+		if c.expectedErr == nil {
+			if err != nil {
+				t.Fatalf("case %d: unexpected error: %s", i, err)
+			}
+			continue
+		}
+
+		if err == nil || !c.expectedErr.MatchString(err.Error()) {
+			t.Fatalf("case %d: expected error matching %q, got %v", i, c.expectedErr, err)
+		}
+	}
+}
+
 // TODO: func_ TestAccNewRelicNrqlAlertCondition_Multi(t *testing.T) {
 
 func testAccCheckNewRelicNrqlAlertConditionDestroy(s *terraform.State) error {
-	client := testAccProvider.Meta().(*newrelic.Client)
+	client := testAccProvider.Meta().(*ProviderConfig).Client
 	for _, r := range s.RootModule().Resources {
 		if r.Type != "newrelic_nrql_alert_condition" {
 			continue
@@ -117,7 +588,7 @@ func testAccCheckNewRelicNrqlAlertConditionExists(n string) resource.TestCheckFu
 			return fmt.Errorf("No alert condition ID is set")
 		}
 
-		client := testAccProvider.Meta().(*newrelic.Client)
+		client := testAccProvider.Meta().(*ProviderConfig).Client
 
 		ids, err := parseIDs(rs.Primary.ID, 2)
 		if err != nil {
@@ -201,3 +672,127 @@ resource "newrelic_nrql_alert_condition" "foo" {
 }
 
 // TODO: const testAccCheckNewRelicNrqlAlertConditionConfigMulti = `
+
+func testAccCheckNewRelicNrqlAlertConditionConfigCriticalWarning(rName string, warningThreshold string) string {
+	return fmt.Sprintf(`
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_nrql_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name = "tf-test-%[1]s"
+
+  critical {
+    duration      = 5
+    operator      = "below"
+    threshold     = "0.75"
+    time_function = "all"
+  }
+
+  warning {
+    duration      = 5
+    operator      = "below"
+    threshold     = "%[2]s"
+    time_function = "all"
+  }
+
+  nrql {
+    query       = "SELECT uniqueCount(hostname) FROM ComputeSample"
+    since_value = "5"
+  }
+}
+`, rName, warningThreshold)
+}
+
+func testAccCheckNewRelicNrqlAlertConditionConfigBaseline(rName string, direction string) string {
+	return fmt.Sprintf(`
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_nrql_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name               = "tf-test-%[1]s"
+  type               = "baseline"
+  baseline_direction = "%[2]s"
+
+  critical {
+    duration      = 5
+    operator      = "above"
+    threshold     = "3"
+    time_function = "all"
+  }
+
+  nrql {
+    query       = "SELECT count(*) FROM Transaction"
+    since_value = "3"
+  }
+}
+`, rName, direction)
+}
+
+func testAccCheckNewRelicNrqlAlertConditionConfigOutlier(rName string, query string) string {
+	return fmt.Sprintf(`
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_nrql_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name            = "tf-test-%[1]s"
+  type            = "outlier"
+  expected_groups = 2
+  ignore_overlap  = true
+
+  critical {
+    duration      = 5
+    operator      = "above"
+    threshold     = "3"
+    time_function = "all"
+  }
+
+  nrql {
+    query       = "%[2]s"
+    since_value = "3"
+  }
+}
+`, rName, query)
+}
+
+func testAccCheckNewRelicNrqlAlertConditionConfigExpiration(rName string, openOnExpiration string) string {
+	return fmt.Sprintf(`
+
+resource "newrelic_alert_policy" "foo" {
+  name = "tf-test-%[1]s"
+}
+
+resource "newrelic_nrql_alert_condition" "foo" {
+  policy_id = "${newrelic_alert_policy.foo.id}"
+
+  name                           = "tf-test-%[1]s"
+  violation_time_limit_seconds   = 3600
+  expiration_duration            = 300
+  open_violation_on_expiration   = %[2]s
+  close_violations_on_expiration = false
+
+  critical {
+    duration      = 5
+    operator      = "below"
+    threshold     = "1"
+    time_function = "all"
+  }
+
+  nrql {
+    query       = "SELECT count(*) FROM Transaction"
+    since_value = "3"
+  }
+}
+`, rName, openOnExpiration)
+}
@@ -1,7 +1,12 @@
 package newrelic
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"regexp"
+	"strconv"
+	"time"
 
 	"github.com/hashicorp/terraform/helper/schema"
 )
@@ -41,3 +46,286 @@ func intInSlice(valid []int) schema.SchemaValidateFunc {
 		return
 	}
 }
+
+var alertConditionThresholdRegexp = regexp.MustCompile(`^([0-9]*\.?[0-9]+)\s*(ms|s|%|rpm|rps)?$`)
+
+// alertConditionThresholdUnits maps threshold units to their quantity and the
+// factor converting them to the unit the API expects for that quantity.
+var alertConditionThresholdUnits = map[string]struct {
+	quantity string
+	factor   float64
+}{
+	"ms":  {"time", 0.001},
+	"s":   {"time", 1},
+	"%":   {"percent", 1},
+	"rpm": {"throughput", 1},
+	"rps": {"throughput", 60},
+}
+
+// parseAlertConditionThreshold parses a threshold such as "0.75", "500ms",
+// "2.5%" or "1200rpm", returning the value in the API unit and the quantity
+// measured, which is empty for plain numbers.
+func parseAlertConditionThreshold(s string) (float64, string, error) {
+	matches := alertConditionThresholdRegexp.FindStringSubmatch(s)
+	if matches == nil {
+		return 0, "", fmt.Errorf("invalid threshold %q, expected a number optionally followed by one of ms, s, %%, rpm or rps", s)
+	}
+
+	v, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if matches[2] == "" {
+		return v, "", nil
+	}
+
+	unit := alertConditionThresholdUnits[matches[2]]
+
+	return v * unit.factor, unit.quantity, nil
+}
+
+// parseAlertConditionDuration parses a duration in whole minutes, either as
+// a plain number or with a unit such as "10m" or "2h".
+func parseAlertConditionDuration(s string) (int, error) {
+	if v, err := strconv.Atoi(s); err == nil {
+		return v, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, expected minutes such as 10 or 10m", s)
+	}
+
+	if d%time.Minute != 0 {
+		return 0, fmt.Errorf("invalid duration %q, must be a whole number of minutes", s)
+	}
+
+	return int(d / time.Minute), nil
+}
+
+func validateAlertConditionThreshold(i interface{}, k string) (s []string, es []error) {
+	v, ok := i.(string)
+	if !ok {
+		es = append(es, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	if _, _, err := parseAlertConditionThreshold(v); err != nil {
+		es = append(es, fmt.Errorf("%s: %s", k, err))
+	}
+
+	return
+}
+
+func validateAlertConditionDuration(valid []int) schema.SchemaValidateFunc {
+	return func(i interface{}, k string) (s []string, es []error) {
+		v, ok := i.(string)
+		if !ok {
+			es = append(es, fmt.Errorf("expected type of %s to be string", k))
+			return
+		}
+
+		minutes, err := parseAlertConditionDuration(v)
+		if err != nil {
+			es = append(es, fmt.Errorf("%s: %s", k, err))
+			return
+		}
+
+		return intInSlice(valid)(minutes, k)
+	}
+}
+
+// validateNrql checks the syntax of an NRQL query.
+func validateNrql(i interface{}, k string) (s []string, es []error) {
+	v, ok := i.(string)
+	if !ok {
+		es = append(es, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	if v == "" {
+		return
+	}
+
+	if _, err := parseNrql(v); err != nil {
+		es = append(es, fmt.Errorf("%s: invalid NRQL: %s", k, err))
+	}
+
+	return
+}
+
+// validateNrqlAlertQuery checks the syntax of an NRQL alert condition query,
+// and that it has none of the clauses alert conditions do not allow.
+func validateNrqlAlertQuery(i interface{}, k string) (s []string, es []error) {
+	v, ok := i.(string)
+	if !ok {
+		es = append(es, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	q, err := parseNrql(v)
+	if err != nil {
+		es = append(es, fmt.Errorf("%s: invalid NRQL: %s", k, err))
+		return
+	}
+
+	for _, err := range nrqlAlertQueryErrors(q) {
+		es = append(es, fmt.Errorf("%s: %s", k, err))
+	}
+
+	return
+}
+
+// validateInsightsQuery checks the syntax of an Insights data source query,
+// which cannot use TIMESERIES as its results are not flattened.
+func validateInsightsQuery(i interface{}, k string) (s []string, es []error) {
+	s, es = validateNrql(i, k)
+	if len(es) > 0 {
+		return
+	}
+
+	if v, _ := i.(string); v != "" {
+		if q, err := parseNrql(v); err == nil && q.clause("TIMESERIES") != nil {
+			es = append(es, fmt.Errorf("%s: TIMESERIES clause is not supported", k))
+		}
+	}
+
+	return
+}
+
+// webhookTemplateVariables are the placeholders New Relic substitutes in
+// webhook payloads and headers.
+var webhookTemplateVariables = []string{
+	"$ACCOUNT_ID",
+	"$ACCOUNT_NAME",
+	"$CLOSED_VIOLATIONS_COUNT_CRITICAL",
+	"$CLOSED_VIOLATIONS_COUNT_WARNING",
+	"$CONDITION_DESCRIPTION",
+	"$CONDITION_ID",
+	"$CONDITION_NAME",
+	"$CREATED_AT",
+	"$CURRENT_STATE",
+	"$DETAILS",
+	"$DURATION",
+	"$EVENT_DETAILS",
+	"$EVENT_SOURCE",
+	"$EVENT_TYPE",
+	"$INCIDENT_ACKNOWLEDGE_URL",
+	"$INCIDENT_ID",
+	"$INCIDENT_URL",
+	"$ISSUE_ID",
+	"$ISSUE_URL",
+	"$METADATA",
+	"$OPEN_VIOLATIONS_COUNT_CRITICAL",
+	"$OPEN_VIOLATIONS_COUNT_WARNING",
+	"$OWNER",
+	"$POLICY_NAME",
+	"$POLICY_URL",
+	"$RUNBOOK_URL",
+	"$SEVERITY",
+	"$TARGETS",
+	"$TIMESTAMP",
+	"$TIMESTAMP_UTC_STRING",
+	"$VIOLATION_CALLBACK_URL",
+	"$VIOLATION_CHART_URL",
+}
+
+var webhookTemplateVariableRegexp = regexp.MustCompile(`\$[A-Z][A-Z0-9_]*`)
+
+var webhookTemplateVariablePrefixRegexp = regexp.MustCompile(`^\$[A-Z][A-Z0-9_]*`)
+
+// quoteWebhookTemplateVariables returns s with each template variable outside
+// of a JSON string, as in {"count": $OPEN_VIOLATIONS_COUNT_CRITICAL}, replaced
+// by a quoted sentinel so that s parses as JSON. The sentinel starts with a NUL
+// character, keeping an unquoted variable distinct from the same variable in a
+// string and from any other variable.
+func quoteWebhookTemplateVariables(s string) string {
+	var b bytes.Buffer
+	quoted := false
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case quoted && c == '\\' && i+1 < len(s):
+			b.WriteString(s[i : i+2])
+			i++
+			continue
+		case c == '"':
+			quoted = !quoted
+		case !quoted && c == '$':
+			if match := webhookTemplateVariablePrefixRegexp.FindString(s[i:]); match != "" {
+				b.WriteString(`"\u0000` + match + `"`)
+				i += len(match) - 1
+				continue
+			}
+		}
+
+		b.WriteByte(c)
+	}
+
+	return b.String()
+}
+
+func validateWebhookTemplateVariables(s string, k string) (es []error) {
+	for _, match := range webhookTemplateVariableRegexp.FindAllString(s, -1) {
+		found := false
+		for _, v := range webhookTemplateVariables {
+			if match == v {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			es = append(es, fmt.Errorf("%s: unknown template variable %s, expected one of %v", k, match, webhookTemplateVariables))
+		}
+	}
+
+	return
+}
+
+// validateWebhookJSON checks that a webhook payload or headers value is a
+// JSON object once template variables, which may appear unquoted, are
+// substituted.
+func validateWebhookJSON(i interface{}, k string) (s []string, es []error) {
+	v, ok := i.(string)
+	if !ok {
+		es = append(es, fmt.Errorf("expected type of %s to be string", k))
+		return
+	}
+
+	if v == "" {
+		return
+	}
+
+	es = append(es, validateWebhookTemplateVariables(v, k)...)
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal([]byte(quoteWebhookTemplateVariables(v)), &obj); err != nil {
+		es = append(es, fmt.Errorf("%s: must be a JSON object: %s", k, err))
+	}
+
+	return
+}
+
+func validateWebhookMap(i interface{}, k string) (s []string, es []error) {
+	m, ok := i.(map[string]interface{})
+	if !ok {
+		es = append(es, fmt.Errorf("expected type of %s to be map", k))
+		return
+	}
+
+	for key, v := range m {
+		value, ok := v.(string)
+		if !ok {
+			es = append(es, fmt.Errorf("%s.%s: map values must be strings, use the JSON string form for numbers, booleans and nested objects", k, key))
+			continue
+		}
+
+		es = append(es, validateWebhookTemplateVariables(value, fmt.Sprintf("%s.%s", k, key))...)
+	}
+
+	return
+}
@@ -55,6 +55,229 @@ func TestValidationFloat64Gte(t *testing.T) {
 	})
 }
 
+func TestValidationWebhookJSON(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: `{"id": "$INCIDENT_ID", "policy": "$POLICY_NAME"}`,
+			f:   validateWebhookJSON,
+		},
+		{
+			val: `{"count": $OPEN_VIOLATIONS_COUNT_CRITICAL}`,
+			f:   validateWebhookJSON,
+		},
+		{
+			val: "",
+			f:   validateWebhookJSON,
+		},
+		{
+			val: `{"id": "\"$INCIDENT_ID\"", "count": $OPEN_VIOLATIONS_COUNT_CRITICAL}`,
+			f:   validateWebhookJSON,
+		},
+		{
+			val:         `{"id": "$INCIDENT_ID"`,
+			f:           validateWebhookJSON,
+			expectedErr: regexp.MustCompile("must be a JSON object"),
+		},
+		{
+			val:         `["$INCIDENT_ID"]`,
+			f:           validateWebhookJSON,
+			expectedErr: regexp.MustCompile("must be a JSON object"),
+		},
+		{
+			val:         `{"policy": "$POLICY_NAMES"}`,
+			f:           validateWebhookJSON,
+			expectedErr: regexp.MustCompile("unknown template variable \\$POLICY_NAMES"),
+		},
+	})
+}
+
+func TestValidationWebhookMap(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: map[string]interface{}{"X-Incident": "$INCIDENT_ID"},
+			f:   validateWebhookMap,
+		},
+		{
+			val:         map[string]interface{}{"X-Incident": "$INCIDENTID"},
+			f:           validateWebhookMap,
+			expectedErr: regexp.MustCompile("unknown template variable \\$INCIDENTID"),
+		},
+		{
+			val:         map[string]interface{}{"count": 1},
+			f:           validateWebhookMap,
+			expectedErr: regexp.MustCompile("map values must be strings"),
+		},
+	})
+}
+
+func TestValidationAlertConditionThreshold(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: "0.75",
+			f:   validateAlertConditionThreshold,
+		},
+		{
+			val: "500ms",
+			f:   validateAlertConditionThreshold,
+		},
+		{
+			val: "2.5%",
+			f:   validateAlertConditionThreshold,
+		},
+		{
+			val: "1200rpm",
+			f:   validateAlertConditionThreshold,
+		},
+		{
+			val:         "-1",
+			f:           validateAlertConditionThreshold,
+			expectedErr: regexp.MustCompile("invalid threshold"),
+		},
+		{
+			val:         "500 furlongs",
+			f:           validateAlertConditionThreshold,
+			expectedErr: regexp.MustCompile("invalid threshold"),
+		},
+	})
+}
+
+func TestValidationAlertConditionDuration(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: "10",
+			f:   validateAlertConditionDuration([]int{5, 10, 60}),
+		},
+		{
+			val: "10m",
+			f:   validateAlertConditionDuration([]int{5, 10, 60}),
+		},
+		{
+			val: "1h",
+			f:   validateAlertConditionDuration([]int{5, 10, 60}),
+		},
+		{
+			val:         "90s",
+			f:           validateAlertConditionDuration([]int{5, 10, 60}),
+			expectedErr: regexp.MustCompile("whole number of minutes"),
+		},
+		{
+			val:         "20m",
+			f:           validateAlertConditionDuration([]int{5, 10, 60}),
+			expectedErr: regexp.MustCompile("to be one of \\[5 10 60\\], got 20"),
+		},
+	})
+}
+
+func TestValidationNrql(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: "SELECT PERCENTILE(duration, 95) from Transaction FACET appName TIMESERIES auto",
+			f:   validateNrql,
+		},
+		{
+			val: "SELECT count(*) FROM PageView SINCE 1 week ago",
+			f:   validateNrql,
+		},
+		{
+			val: "",
+			f:   validateNrql,
+		},
+		{
+			val: "SHOW EVENT TYPES SINCE 1 week ago",
+			f:   validateNrql,
+		},
+		{
+			val:         "SELECT count(*) FROM SyntheticCheck WHERE monitorId = 'abc",
+			f:           validateNrql,
+			expectedErr: regexp.MustCompile("unterminated string at position 55"),
+		},
+		{
+			val:         "SELECT count(* FROM Transaction",
+			f:           validateNrql,
+			expectedErr: regexp.MustCompile("unclosed '\\(' at position 13"),
+		},
+	})
+}
+
+func TestValidationNrqlAlertQuery(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: "SELECT average(duration) FROM Transaction FACET host",
+			f:   validateNrqlAlertQuery,
+		},
+		{
+			val:         "SELECT count(*)",
+			f:           validateNrqlAlertQuery,
+			expectedErr: regexp.MustCompile("query has no FROM clause"),
+		},
+		{
+			val:         "SHOW EVENT TYPES",
+			f:           validateNrqlAlertQuery,
+			expectedErr: regexp.MustCompile("query has no SELECT clause"),
+		},
+		{
+			val:         "SELECT count(*) FROM Transaction TIMESERIES",
+			f:           validateNrqlAlertQuery,
+			expectedErr: regexp.MustCompile("TIMESERIES clause is not allowed"),
+		},
+		{
+			val:         "SELECT count(*) FROM Transaction compare with 1 week ago",
+			f:           validateNrqlAlertQuery,
+			expectedErr: regexp.MustCompile("COMPARE WITH clause is not allowed"),
+		},
+		{
+			val:         "SELECT count(*) FROM Transaction WHERE",
+			f:           validateNrqlAlertQuery,
+			expectedErr: regexp.MustCompile("WHERE clause at position 34 is missing its arguments"),
+		},
+	})
+}
+
+func TestValidationInsightsQuery(t *testing.T) {
+	runTestCases(t, []testCase{
+		{
+			val: "SELECT count(*) FROM Transaction FACET appName SINCE 1 day ago",
+			f:   validateInsightsQuery,
+		},
+		{
+			val:         "SELECT count(*) FROM Transaction TIMESERIES 1 hour",
+			f:           validateInsightsQuery,
+			expectedErr: regexp.MustCompile("TIMESERIES clause is not supported"),
+		},
+		{
+			val:         "SELECT count(* FROM Transaction",
+			f:           validateInsightsQuery,
+			expectedErr: regexp.MustCompile("invalid NRQL"),
+		},
+	})
+}
+
+func TestParseAlertConditionThreshold(t *testing.T) {
+	cases := []struct {
+		val      string
+		value    float64
+		quantity string
+	}{
+		{"0.75", 0.75, ""},
+		{"500ms", 0.5, "time"},
+		{"2s", 2, "time"},
+		{"2.5%", 2.5, "percent"},
+		{"1200rpm", 1200, "throughput"},
+		{"20rps", 1200, "throughput"},
+	}
+
+	for _, c := range cases {
+		value, quantity, err := parseAlertConditionThreshold(c.val)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", c.val, err)
+		}
+
+		if value != c.value || quantity != c.quantity {
+			t.Fatalf("%s: expected %v %q, got %v %q", c.val, c.value, c.quantity, value, quantity)
+		}
+	}
+}
+
 func runTestCases(t *testing.T, cases []testCase) {
 	matchErr := func(errs []error, r *regexp.Regexp) bool {
 		// err must match one provided